@@ -5,6 +5,7 @@ go 1.22
 require (
 	github.com/gorilla/feeds v1.1.2
 	github.com/mmcdole/gofeed v1.3.0
+	golang.org/x/net v0.22.0
 )
 
 require (
@@ -15,6 +16,5 @@ require (
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
-	golang.org/x/net v0.22.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the override as accepted by /admin/override: none, open, or
+// closed.
+func (o Override) String() string {
+	switch o {
+	case Open:
+		return "open"
+	case Closed:
+		return "closed"
+	default:
+		return "none"
+	}
+}
+
+const (
+	// maxAdminBody bounds the body of a signed admin request.
+	maxAdminBody = 1 << 20
+	// maxAdminSkew is how far a signed request's timestamp may be from the
+	// server's clock.
+	maxAdminSkew = 5 * time.Minute
+)
+
+// adminSignature returns the signature of an admin request: a hex
+// HMAC-SHA256, keyed with the admin key, of the method, request URI,
+// timestamp (Unix seconds), and body, each followed by a newline. Clients
+// send it in X-Flood-Signature and the timestamp in X-Flood-Timestamp.
+func adminSignature(key []byte, method, uri, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", method, uri, timestamp)
+	mac.Write(body)
+	mac.Write([]byte("\n"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authorized reports whether r carries the admin bearer token or a valid
+// signature. A signature is valid within maxAdminSkew of its timestamp, so
+// a captured request can be replayed only briefly.
+func (h *handler) authorized(r *http.Request) bool {
+	if h.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1 {
+			return true
+		}
+	}
+	sig := r.Header.Get("X-Flood-Signature")
+	if len(h.adminKey) == 0 || sig == "" {
+		return false
+	}
+	ts := r.Header.Get("X-Flood-Timestamp")
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := time.Since(time.Unix(secs, 0)); skew > maxAdminSkew || skew < -maxAdminSkew {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAdminBody))
+	if err != nil {
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	want := adminSignature(h.adminKey, r.Method, r.URL.RequestURI(), ts, body)
+	return hmac.Equal([]byte(sig), []byte(want))
+}
+
+// admin requires the request to be authorized before calling hf.
+func (h *handler) admin(hf http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		hf(w, r)
+	}
+}
+
+// setOverride sets the manual override at runtime from the "status" form
+// value: open, closed, or none to go back to the provider. It takes effect
+// on the next request.
+func (h *handler) setOverride(w http.ResponseWriter, r *http.Request) {
+	var o Override
+	switch s := r.FormValue("status"); s {
+	case "none":
+		o = None
+	case "open":
+		o = Open
+	case "closed":
+		o = Closed
+	default:
+		http.Error(w, fmt.Sprintf("status must be open, closed, or none, not %q", s), http.StatusBadRequest)
+		return
+	}
+	if prev := Override(h.override.Swap(int32(o))); prev != o {
+		log.Printf("Manual override changed from %s to %s by %s", prev, o, remoteAddr(r))
+	}
+	fmt.Fprintln(w, o)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminOverride(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", AdminToken: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	override := func(token, status string) int {
+		r := httptest.NewRequest(http.MethodPost, "/admin/override", strings.NewReader(url.Values{"status": {status}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	status := func() string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status.txt", nil))
+		return rec.Header().Get("X-Road-Status")
+	}
+
+	if got := status(); got != "closed" {
+		t.Fatalf("Expected closed from the provider, got %s", got)
+	}
+	if code := override("", "open"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	if code := override("wrong", "open"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with the wrong token, got %d", code)
+	}
+	if code := override("s3cret", "sideways"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad status, got %d", code)
+	}
+	if code := override("s3cret", "open"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if got := status(); got != "open" {
+		t.Errorf("Expected the override to apply, got %s", got)
+	}
+	if cs := h.(*handler).tracker.changesSince(time.Time{}); len(cs) != 1 || cs[0].Source != "override" {
+		t.Errorf("Expected the override to be recorded as a change, got %+v", cs)
+	}
+	override("s3cret", "none")
+	if got := status(); got != "closed" {
+		t.Errorf("Expected the provider's status after clearing the override, got %s", got)
+	}
+}
+
+func TestAdminDisabled(t *testing.T) {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/admin/override?status=closed", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if o := Override(h.(*handler).override.Load()); o != None {
+		t.Errorf("Expected no admin API without a token, but the override is %s", o)
+	}
+}
+
+// signAdminRequest signs r with key, as a client of the admin API would.
+// body must be r's body.
+func signAdminRequest(r *http.Request, key, body []byte, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set("X-Flood-Timestamp", ts)
+	r.Header.Set("X-Flood-Signature", adminSignature(key, r.Method, r.URL.RequestURI(), ts, body))
+}
+
+func TestAdminSigned(t *testing.T) {
+	key := []byte("k3y")
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th", AdminKey: key})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	override := func(sign func(r *http.Request, body []byte)) int {
+		body := []byte(url.Values{"status": {"closed"}}.Encode())
+		r := httptest.NewRequest(http.MethodPost, "/admin/override", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		sign(r, body)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	for _, tc := range []struct {
+		desc string
+		sign func(r *http.Request, body []byte)
+		want int
+	}{{
+		desc: "unsigned",
+		sign: func(*http.Request, []byte) {},
+		want: http.StatusUnauthorized,
+	}, {
+		desc: "wrong key",
+		sign: func(r *http.Request, body []byte) { signAdminRequest(r, []byte("nope"), body, time.Now()) },
+		want: http.StatusUnauthorized,
+	}, {
+		desc: "tampered body",
+		sign: func(r *http.Request, body []byte) { signAdminRequest(r, key, []byte("status=open"), time.Now()) },
+		want: http.StatusUnauthorized,
+	}, {
+		desc: "stale",
+		sign: func(r *http.Request, body []byte) { signAdminRequest(r, key, body, time.Now().Add(-time.Hour)) },
+		want: http.StatusUnauthorized,
+	}, {
+		desc: "bearer without a token configured",
+		sign: func(r *http.Request, body []byte) { r.Header.Set("Authorization", "Bearer ") },
+		want: http.StatusUnauthorized,
+	}, {
+		desc: "signed",
+		sign: func(r *http.Request, body []byte) { signAdminRequest(r, key, body, time.Now()) },
+		want: http.StatusOK,
+	}} {
+		if got := override(tc.sign); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.desc, tc.want, got)
+		}
+	}
+	if o := Override(h.(*handler).override.Load()); o != Closed {
+		t.Errorf("Expected the signed request to set the override, got %s", o)
+	}
+}
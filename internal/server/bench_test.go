@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// benchAlerts is a feed's worth of alerts, a few of them for the road.
+func benchAlerts() []*Alert {
+	alerts := []*Alert{
+		{Status: StatusClosed, Reason: ReasonFlooding, Title: "Closed - NE 124th St", Description: "Flooding between SR 203 and W Snoqualmie Valley Rd"},
+		{Status: StatusAdvisory, Title: "Water over roadway - NE 124th St at the bridge"},
+	}
+	for i := 0; i < 50; i++ {
+		alerts = append(alerts, &Alert{Status: StatusClosed, Reason: ReasonConstruction, Title: "Closed - Some other road"})
+	}
+	return alerts
+}
+
+func benchHandler(b testing.TB) http.Handler {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{alerts: benchAlerts()}, Road: "124th"})
+	if err != nil {
+		b.Fatalf("NewHandler failed: %v", err)
+	}
+	// Request logs would dominate the measurements.
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(out) })
+	return h
+}
+
+func benchmarkPath(b *testing.B, path string) {
+	h := benchHandler(b)
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkPage(b *testing.B)      { benchmarkPath(b, "/") }
+func BenchmarkAPIStatus(b *testing.B) { benchmarkPath(b, "/api/v1/status") }
+
+func BenchmarkEvaluate(b *testing.B) {
+	h := benchHandler(b).(*handler)
+	alerts := benchAlerts()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.evaluate(alerts)
+	}
+}
+
+func BenchmarkMarshalStatus(b *testing.B) {
+	h := benchHandler(b).(*handler)
+	td := h.evaluate(benchAlerts())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(td); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestAllocBudgets guards the hot paths against regressions that would slow
+// page loads during a flood. Budgets have some headroom; raise them
+// deliberately, not to make a failure go away.
+func TestAllocBudgets(t *testing.T) {
+	h := benchHandler(t)
+	page := httptest.NewRequest(http.MethodGet, "/", nil)
+	api := httptest.NewRequest(http.MethodGet, "/api/v1/status", nil)
+	td := h.(*handler).evaluate(benchAlerts())
+	alerts := benchAlerts()
+	for _, tc := range []struct {
+		name   string
+		budget float64
+		f      func()
+	}{
+		{"page", 250, func() { h.ServeHTTP(httptest.NewRecorder(), page) }},
+		{"api", 50, func() { h.ServeHTTP(httptest.NewRecorder(), api) }},
+		{"evaluate", 5, func() { h.(*handler).evaluate(alerts) }},
+		{"marshal", 4, func() { json.Marshal(td) }},
+	} {
+		if got := testing.AllocsPerRun(100, tc.f); got > tc.budget {
+			t.Errorf("%s: %.0f allocations, over the budget of %.0f", tc.name, got, tc.budget)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Bundle is a summary page for a group of nearby roads, such as the
+// crossings of a river valley. Each road is matched in alert titles like a
+// Segment.
+type Bundle struct {
+	// Name is the page title, e.g. "Snoqualmie Valley".
+	Name string
+	// Path is where the page is served, e.g. "/valley".
+	Path  string
+	Roads []Segment
+}
+
+// bundleData contains the fields needed to populate the bundle.html
+// template.
+type bundleData struct {
+	Name       string
+	Roads      []*segmentData
+	Source     string
+	SourceLink string
+	// Unknown explains why every road is StatusUnknown, if the alerts
+	// couldn't be fetched.
+	Unknown string
+}
+
+// bundlePage renders the status of every road in the bundle as a table.
+// The manual override applies only to the primary road, so it is ignored
+// here. If the alerts can't be fetched, every road is shown as Unknown.
+func (h *handler) bundlePage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bd := &bundleData{Name: h.bundle.Name}
+		bd.Source, bd.SourceLink = h.provider.Source()
+		alerts, err := h.alerts(r)
+		failed := err != nil
+		switch {
+		case errors.Is(err, errFeedMuted):
+			bd.Unknown = mutedUnknown
+		case failed:
+			h.errorf(r, "failed to fetch road alerts: %v", err)
+			bd.Unknown = "The road alert feed couldn't be reached."
+			if bd.Source != "" {
+				bd.Unknown = fmt.Sprintf("The %s road alert feed couldn't be reached.", bd.Source)
+			}
+		}
+		for _, road := range h.bundle.Roads {
+			if failed {
+				bd.Roads = append(bd.Roads, &segmentData{Name: road.Name, Status: StatusUnknown})
+			} else {
+				bd.Roads = append(bd.Roads, h.segmentStatus(road, alerts))
+			}
+		}
+		var b bytes.Buffer
+		if err := h.bundleT.Execute(&b, bd); err != nil {
+			h.internalError(w, r, "internal error: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if failed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(b.Bytes())
+	}
+}
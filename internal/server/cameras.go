@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCameraSize bounds a camera image download.
+	maxCameraSize = 5 << 20
+	// cameraTimeout bounds a camera image download.
+	cameraTimeout = 10 * time.Second
+	// cameraTTL is how long a camera image is served from the cache.
+	cameraTTL = 30 * time.Second
+	// cameraStale is how long a cached image may still be served while the
+	// camera is failing.
+	cameraStale = 10 * time.Minute
+)
+
+// Camera is a traffic camera shown on the page. Cameras with the same Group
+// are shown together under its heading.
+type Camera struct {
+	Group string
+	Name  string
+	URL   string
+}
+
+// cameraGroup is a heading and its cameras, as rendered on the page.
+type cameraGroup struct {
+	Name    string
+	Cameras []*cameraData
+}
+
+// cameraData is a camera as rendered on the page.
+type cameraData struct {
+	Name string
+	Src  string
+}
+
+// cameras proxies the configured cameras.
+type cameras struct {
+	list      []Camera
+	cache     []*cameraCache
+	groups    []*cameraGroup
+	client    *http.Client
+	userAgent string
+}
+
+// newCameras sets up the proxy for the cameras in opts, checking their URLs
+// against the outbound policy.
+func newCameras(opts *Options) (*cameras, error) {
+	policy := opts.Outbound
+	if policy == nil {
+		policy = &OutboundPolicy{AllowPrivate: true}
+	}
+	c := &cameras{
+		list:      opts.Cameras,
+		groups:    cameraGroups(opts.Cameras),
+		client:    policy.Client(cameraTimeout),
+		userAgent: opts.UserAgent,
+	}
+	if c.userAgent == "" {
+		c.userAgent = DefaultUserAgent
+	}
+	for _, cam := range opts.Cameras {
+		u, err := url.Parse(cam.URL)
+		if err != nil {
+			return nil, fmt.Errorf("camera %q: %w", cam.Name, err)
+		}
+		if err := policy.Check(u); err != nil {
+			return nil, fmt.Errorf("camera %q: %w", cam.Name, err)
+		}
+		c.cache = append(c.cache, &cameraCache{})
+	}
+	return c, nil
+}
+
+// cameraCache holds the latest image from a camera.
+type cameraCache struct {
+	mu          sync.Mutex
+	body        []byte
+	contentType string
+	fetched     time.Time
+	// err is why the last refresh failed, if it did.
+	err error
+	// refreshing is closed when the refresh in progress, if any, finishes.
+	refreshing chan struct{}
+}
+
+// refresh starts downloading the camera's image, unless a download is
+// already in progress, and returns a channel that is closed when it
+// finishes. The download isn't tied to any request, so a client that gives
+// up doesn't cancel it for the others. c.mu must be held.
+func (c *cameraCache) refresh(cs *cameras, url string) <-chan struct{} {
+	if c.refreshing != nil {
+		return c.refreshing
+	}
+	done := make(chan struct{})
+	c.refreshing = done
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cameraTimeout)
+		defer cancel()
+		body, contentType, err := cs.fetch(ctx, url)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.err = err; err == nil {
+			c.body, c.contentType, c.fetched = body, contentType, time.Now()
+		}
+		c.refreshing = nil
+		close(done)
+	}()
+	return done
+}
+
+// cameraGroups groups cameras for the page, keeping their order, with
+// images served by the proxy.
+func cameraGroups(cameras []Camera) []*cameraGroup {
+	var groups []*cameraGroup
+	for i, c := range cameras {
+		if len(groups) == 0 || groups[len(groups)-1].Name != c.Group {
+			groups = append(groups, &cameraGroup{Name: c.Group})
+		}
+		g := groups[len(groups)-1]
+		g.Cameras = append(g.Cameras, &cameraData{Name: c.Name, Src: fmt.Sprintf("/cameras/%d.jpg", i)})
+	}
+	return groups
+}
+
+// camera proxies the image from a configured camera, at /cameras/{n}.jpg.
+// Images are cached briefly, and a recent image is served if the camera is
+// failing, since the county's camera hosts are flaky. An expired image is
+// refreshed in the background while it's still served.
+func (h *handler) camera(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("file"), ".jpg"))
+	if err != nil || n < 0 || n >= len(h.cameras.list) {
+		http.NotFound(w, r)
+		return
+	}
+	cam, c := h.cameras.list[n], h.cameras.cache[n]
+	c.mu.Lock()
+	if c.body == nil || time.Since(c.fetched) > cameraTTL {
+		done := c.refresh(h.cameras, cam.URL)
+		if c.body == nil || time.Since(c.fetched) > cameraStale {
+			// There's nothing fit to serve until the refresh finishes.
+			c.mu.Unlock()
+			select {
+			case <-done:
+			case <-r.Context().Done():
+				return
+			}
+			c.mu.Lock()
+		}
+	}
+	body, contentType, fetched, err := c.body, c.contentType, c.fetched, c.err
+	c.mu.Unlock()
+	switch {
+	case body == nil || time.Since(fetched) > cameraStale:
+		h.warnf(r, "camera %q failed: %v", cam.Name, err)
+		http.Error(w, "camera unavailable", http.StatusBadGateway)
+		return
+	case err != nil:
+		h.warnf(r, "serving cached image for camera %q: %v", cam.Name, err)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cameraTTL/time.Second)))
+	w.Header().Set("Last-Modified", fetched.UTC().Format(http.TimeFormat))
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// fetchCamera downloads a camera image, rejecting anything that isn't an
+// image or is too large.
+func (cs *cameras) fetch(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", cs.userAgent)
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); err != nil || !strings.HasPrefix(mt, "image/") {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+	if resp.ContentLength > maxCameraSize {
+		return nil, "", fmt.Errorf("image is %d bytes", resp.ContentLength)
+	}
+	body, err := readLimited(resp.Body, maxCameraSize)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, contentType, nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCameras(t *testing.T) {
+	var hits atomic.Int32
+	var broken atomic.Bool
+	upstream := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if broken.Load() {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/html" {
+			w.Header().Set("Content-Type", "text/html")
+		} else {
+			w.Header().Set("Content-Type", "image/jpeg")
+		}
+		w.Write([]byte("jpeg"))
+	}))
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{},
+		Road:     "124th",
+		Cameras: []Camera{
+			{Group: "124th Cameras", Name: "Roundabout", URL: upstream + "/roundabout.jpg"},
+			{Group: "124th Cameras", Name: "Not an image", URL: upstream + "/html"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	// Otherwise shutting down upstream waits on the proxy's spare
+	// connections.
+	t.Cleanup(h.(*handler).cameras.client.CloseIdleConnections)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	body := get("/").Body.String()
+	for _, want := range []string{"124th Cameras", `src="/cameras/0.jpg"`, `alt="Roundabout"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Page missing %q: %s", want, body)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := get("/cameras/0.jpg")
+		if rec.Code != http.StatusOK || rec.Body.String() != "jpeg" || rec.Header().Get("Content-Type") != "image/jpeg" {
+			t.Errorf("Expected the proxied image, got %d %q", rec.Code, rec.Body)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected the image to be cached, got %d upstream requests", n)
+	}
+
+	// A failing camera serves its last image for a while.
+	broken.Store(true)
+	h.(*handler).cameras.cache[0].fetched = time.Now().Add(-time.Minute)
+	if rec := get("/cameras/0.jpg"); rec.Code != http.StatusOK || rec.Body.String() != "jpeg" {
+		t.Errorf("Expected the cached image, got %d %q", rec.Code, rec.Body)
+	}
+	broken.Store(false)
+
+	if rec := get("/cameras/1.jpg"); rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected non-images to be rejected, got %d", rec.Code)
+	}
+	for _, path := range []string{"/cameras/2.jpg", "/cameras/-1.jpg", "/cameras/x.jpg"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestCamerasOutboundPolicy(t *testing.T) {
+	_, err := NewHandler(&Options{
+		Provider: &fakeProvider{},
+		Road:     "124th",
+		Cameras:  []Camera{{Name: "Internal", URL: "http://metadata.internal/"}},
+		Outbound: &OutboundPolicy{Hosts: []string{"info.kingcounty.gov"}},
+	})
+	if err == nil {
+		t.Error("Expected a disallowed camera URL to be rejected")
+	}
+}
+
+func TestCameraRefresh(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	upstream := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg"))
+	}))
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{},
+		Road:     "124th",
+		Cameras:  []Camera{{Name: "Roundabout", URL: upstream + "/roundabout.jpg"}},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	t.Cleanup(h.(*handler).cameras.client.CloseIdleConnections)
+
+	// A client that gives up doesn't cancel the download for the others,
+	// and concurrent clients share one download.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/cameras/0.jpg", nil).WithContext(ctx))
+	recs := make([]*httptest.ResponseRecorder, 3)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, "/cameras/0.jpg", nil))
+		}()
+	}
+	close(release)
+	wg.Wait()
+	for _, rec := range recs {
+		if rec.Code != http.StatusOK || rec.Body.String() != "jpeg" {
+			t.Errorf("Expected the image, got %d %q", rec.Code, rec.Body)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected one upstream request, got %d", n)
+	}
+
+	// An expired image is served while it's refreshed.
+	c := h.(*handler).cameras.cache[0]
+	c.mu.Lock()
+	c.fetched = time.Now().Add(-time.Minute)
+	c.mu.Unlock()
+	release = make(chan struct{})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cameras/0.jpg", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "jpeg" {
+		t.Errorf("Expected the expired image while refreshing, got %d %q", rec.Code, rec.Body)
+	}
+	close(release)
+}
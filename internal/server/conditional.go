@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// version is a response body's ETag and when it first appeared.
+type version struct {
+	etag     string
+	modified time.Time
+}
+
+// versions remembers when each response last changed, since the status
+// alone doesn't say when a rendered page (with its reports, times, and so
+// on) last changed.
+type versions struct {
+	mu  sync.Mutex
+	now func() time.Time
+	m   map[string]version
+}
+
+func newVersions() *versions {
+	return &versions{now: time.Now, m: make(map[string]version)}
+}
+
+// modified returns when the response named key last changed, given its
+// current ETag.
+func (v *versions) modified(key, etag string) time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	cur, ok := v.m[key]
+	if ok && cur.etag == etag {
+		return cur.modified
+	}
+	// Last-Modified has one-second resolution, so make sure a change
+	// within the same second still moves it forward.
+	modified := v.now().Truncate(time.Second)
+	if ok && !modified.After(cur.modified) {
+		modified = cur.modified.Add(time.Second)
+	}
+	v.m[key] = version{etag, modified}
+	return modified
+}
+
+// serveConditional writes body with an ETag and Last-Modified, answering
+// If-None-Match and If-Modified-Since with 304 Not Modified when the client
+// already has it, and with Cache-Control and Expires if a max age is set.
+// Content-Type must already be set. key names the response, e.g. the route,
+// for tracking its Last-Modified time.
+func (h *handler) serveConditional(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	if h.maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge/time.Second)))
+		w.Header().Set("Expires", time.Now().Add(h.maxAge).UTC().Format(http.TimeFormat))
+	}
+	http.ServeContent(w, r, "", h.versions.modified(key, etag), bytes.NewReader(body))
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConditionalGet(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, path := range []string{"/", "/api/v1/status", "/status.txt"} {
+		rec := get(path)
+		etag, modified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+		if rec.Code != http.StatusOK || etag == "" || modified == "" {
+			t.Fatalf("%s: expected 200 with ETag and Last-Modified, got %d %v", path, rec.Code, rec.Header())
+		}
+		if rec := get(path, "If-None-Match", etag); rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for matching ETag, got %d", path, rec.Code)
+		}
+		if rec := get(path, "If-Modified-Since", modified); rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for If-Modified-Since, got %d", path, rec.Code)
+		}
+		if rec := get(path, "If-None-Match", `"stale"`); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for stale ETag, got %d", path, rec.Code)
+		}
+	}
+
+	etag := get("/").Header().Get("ETag")
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	rec := get("/", "If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new page after the status changed, got %d", rec.Code)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	v := newVersions()
+	first := v.modified("/", `"a"`)
+	if got := v.modified("/", `"a"`); !got.Equal(first) {
+		t.Errorf("Expected unchanged body to keep %v, got %v", first, got)
+	}
+	if got := v.modified("/", `"b"`); !got.After(first) {
+		t.Errorf("Expected a changed body to move past %v, got %v", first, got)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th", MaxAge: 2 * time.Minute})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	for _, path := range []string{"/", "/api/v1/status", "/status.txt"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != "public, max-age=120" {
+			t.Errorf("%s: expected Cache-Control with max-age=120, got %q", path, got)
+		}
+		expires, err := http.ParseTime(rec.Header().Get("Expires"))
+		if err != nil {
+			t.Errorf("%s: invalid Expires: %v", path, err)
+		} else if d := time.Until(expires); d < time.Minute || d > 2*time.Minute {
+			t.Errorf("%s: expected Expires about two minutes out, got %s", path, d)
+		}
+	}
+
+	h, _ = NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th"})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Expected no Cache-Control by default, got %q", got)
+	}
+}
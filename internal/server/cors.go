@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+)
+
+// cors adds CORS headers to a read-only endpoint, so that browser-based
+// dashboards on the allowed origins can fetch it, and answers preflight
+// requests. Requests from other origins are served as usual; browsers just
+// won't let their scripts read the response.
+func (h *handler) cors(hf http.HandlerFunc) http.HandlerFunc {
+	if len(h.origins) == 0 {
+		return hf
+	}
+	all := slices.Contains(h.origins, "*")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin != "" && (all || slices.Contains(h.origins, origin)) {
+			if all {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Road-Status")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD")
+				w.Header().Set("Access-Control-Allow-Headers", "If-None-Match, If-Modified-Since")
+				w.Header().Set("Access-Control-Max-Age", "86400")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		hf(w, r)
+	}
+}
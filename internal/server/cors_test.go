@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider:       &fakeProvider{},
+		Road:           "124th",
+		AllowedOrigins: []string{"https://dash.example"},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1/status", nil)
+		r.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "https://dash.example")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example" {
+		t.Errorf("Expected allowed origin, got %q", got)
+	}
+	if rec := do(http.MethodGet, "https://evil.example"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for other origins, got %v", rec.Header())
+	}
+	rec = do(http.MethodOptions, "https://dash.example")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Expected preflight response, got %d %v", rec.Code, rec.Header())
+	}
+
+	// The HTML page isn't part of the API.
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://dash.example")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers on the page, got %q", got)
+	}
+}
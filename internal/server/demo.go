@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// demoScript is the sequence of alerts a DemoProvider cycles through, one
+// per step, covering every status the page can show. Titles are formats for
+// the road's name.
+var demoScript = []*Alert{
+	nil,
+	{
+		Status:      StatusAdvisory,
+		Reason:      ReasonFlooding,
+		Title:       "Water over roadway - %s",
+		Description: "Use caution.\nWater is over the roadway near the bridge.",
+	},
+	{
+		Status:      StatusPartial,
+		Reason:      ReasonFlooding,
+		Title:       "One lane open with flaggers - %s",
+		Description: "Expect delays.",
+	},
+	{
+		Status:      StatusClosed,
+		Reason:      ReasonFlooding,
+		Title:       "Closed - %s - Flooding",
+		Description: "Expected duration: 2 days.\nDetour via SR 203 and Woodinville-Duvall Rd.",
+	},
+	{
+		Status: StatusOpen,
+		Title:  "Reopened - %s",
+	},
+}
+
+// DemoProvider is a Provider that cycles through scripted alerts (open,
+// advisory, partial, closed, reopened) so that every state of the page can
+// be seen without waiting for a real flood.
+type DemoProvider struct {
+	// Road is mentioned in every scripted alert; it should match the
+	// handler's road.
+	Road string
+	// Interval is how long each scripted state lasts. It must be positive.
+	Interval time.Duration
+	start    time.Time
+	now      func() time.Time
+}
+
+// Demo returns a DemoProvider for road that advances every interval.
+func Demo(road string, interval time.Duration) *DemoProvider {
+	return &DemoProvider{Road: road, Interval: interval, start: time.Now(), now: time.Now}
+}
+
+// Alerts implements Provider.
+func (d *DemoProvider) Alerts(ctx context.Context) ([]*Alert, error) {
+	if d.Interval <= 0 {
+		return nil, fmt.Errorf("demo interval must be positive, not %s", d.Interval)
+	}
+	now := d.now()
+	step := int(now.Sub(d.start)/d.Interval) % len(demoScript)
+	a := demoScript[step]
+	if a == nil {
+		return nil, nil
+	}
+	published := d.start.Add(time.Duration(step) * d.Interval)
+	alert := *a
+	alert.Title = fmt.Sprintf(alert.Title, d.Road)
+	alert.Link = "https://github.com/jdtw/flood"
+	alert.Published = &published
+	return []*Alert{&alert}, nil
+}
+
+// Source implements Provider.
+func (d *DemoProvider) Source() (name, link string) {
+	return "demo data", "https://github.com/jdtw/flood"
+}
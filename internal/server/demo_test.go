@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDemoProvider(t *testing.T) {
+	d := Demo("124th", time.Minute)
+	now := d.start
+	d.now = func() time.Time { return now }
+
+	h, err := NewHandler(&Options{Provider: d, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	want := []Status{StatusOpen, StatusAdvisory, StatusPartial, StatusClosed, StatusOpen, StatusOpen}
+	for i, w := range want {
+		alerts, err := d.Alerts(context.Background())
+		if err != nil {
+			t.Fatalf("Alerts failed: %v", err)
+		}
+		if got := h.(*handler).evaluate(alerts).Status; got != w {
+			t.Errorf("Step %d: expected %s, got %s", i, w, got)
+		}
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestDemoInterval(t *testing.T) {
+	if _, err := Demo("124th", 0).Alerts(context.Background()); err == nil {
+		t.Error("Expected an error for a zero interval")
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxDeviceWait caps how long a device may long-poll.
+const maxDeviceWait = 5 * time.Minute
+
+// deviceStatus is a compact status for microcontroller displays. Keys are
+// single letters to keep the payload small enough to parse without a JSON
+// library.
+type deviceStatus struct {
+	// S is the Status as a number: 0 unknown, 1 open, 2 advisory,
+	// 3 partial, 4 closed.
+	S int `json:"s"`
+	// A is how many seconds ago the status began, or -1 if unknown.
+	A int64 `json:"a"`
+}
+
+// device serves a compact status for devices such as ESP32 e-paper
+// displays. If the "since" query parameter matches the current status
+// number, the request is held for up to "wait" seconds (default 60) or
+// until the status changes, so devices can long-poll instead of polling.
+func (h *handler) device(w http.ResponseWriter, r *http.Request) {
+	next := h.tracker.next()
+	td, err := h.current(r)
+	if err != nil {
+		h.deviceUnknown(w, r, err)
+		return
+	}
+
+	if since, err := strconv.Atoi(r.FormValue("since")); err == nil && Status(since) == td.Status {
+		wait := time.Minute
+		if s, err := strconv.Atoi(r.FormValue("wait")); err == nil {
+			wait = time.Duration(s) * time.Second
+		}
+		if wait > maxDeviceWait {
+			wait = maxDeviceWait
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-next:
+			if td, err = h.current(r); err != nil {
+				h.deviceUnknown(w, r, err)
+				return
+			}
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	ds := deviceStatus{S: int(td.Status), A: -1}
+	if !td.since.IsZero() {
+		ds.A = int64(time.Since(td.since) / time.Second)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Road-Status", td.Status.String())
+	json.NewEncoder(w).Encode(ds)
+}
+
+// deviceUnknown logs err and serves the Unknown status in the compact form,
+// with 503, like / does when the status can't be determined.
+func (h *handler) deviceUnknown(w http.ResponseWriter, r *http.Request, err error) {
+	h.unknown(r, err)
+	b, _ := json.Marshal(deviceStatus{S: int(StatusUnknown), A: -1})
+	h.unavailable(w, r, "application/json", append(b, '\n'))
+}
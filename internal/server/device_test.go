@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeviceLongPoll(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+
+	get := func(url string) deviceStatus {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var ds deviceStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &ds); err != nil {
+			t.Errorf("json.Unmarshal(%s) failed: %v", rec.Body, err)
+		}
+		return ds
+	}
+
+	if ds := get("/api/v1/device"); ds.S != int(StatusClosed) || ds.A != -1 {
+		t.Errorf("Expected closed with unknown age, got %+v", ds)
+	}
+
+	done := make(chan deviceStatus)
+	go func() { done <- get("/api/v1/device?since=4&wait=10") }()
+	select {
+	case ds := <-done:
+		t.Fatalf("Long poll returned early: %+v", ds)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fp.set(&Alert{Status: StatusOpen, Title: "Reopened - 124th"})
+	get("/api/v1/device")
+	select {
+	case ds := <-done:
+		if ds.S != int(StatusOpen) || ds.A < 0 {
+			t.Errorf("Expected open with known age, got %+v", ds)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Long poll did not wake on status change")
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// eventsHeartbeat is how often an idle /events stream sends a comment, so
+// proxies don't close it.
+const eventsHeartbeat = 30 * time.Second
+
+// events streams the status as Server-Sent Events. A "status" event, with
+// the same JSON as /api/v1/status, is sent on connect and again whenever the
+// status changes. The first event is the last status published, so opening a
+// stream doesn't fetch the feed once the status is known.
+func (h *handler) events(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.hub.subscribe()
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "too many live connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.hub.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	send := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		fmt.Fprintf(w, format, args...)
+		return rc.Flush() == nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	var last []byte
+	if !h.hub.seeded() {
+		// Nothing has been published yet. Computing the status publishes
+		// it to this client too, unless the feed is muted.
+		td, err := h.current(r)
+		switch {
+		case err != nil:
+			h.warnf(r, "failed to fetch road alerts for /events: %v", err)
+			if !send("event: error\ndata: failed to fetch road alerts\n\n") {
+				return
+			}
+		case !h.hub.seeded():
+			if last, err = json.Marshal(td); err != nil {
+				h.internalError(w, r, "internal error: %v", err)
+				return
+			}
+			if !send("event: status\ndata: %s\n\n", last) {
+				return
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case data, ok := <-sub.updates:
+			if !ok {
+				h.warnf(r, "evicting /events client that stopped reading")
+				return
+			}
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			if !send("event: status\ndata: %s\n\n", data) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(": heartbeat\n\n") {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	server := startTestServer(t, h)
+	resp, err := http.Get(server + "/events")
+	if err != nil {
+		t.Fatalf("http.Get(/events) failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", got)
+	}
+
+	events := bufio.NewScanner(resp.Body)
+	status := func() Status {
+		t.Helper()
+		for events.Scan() {
+			data, ok := strings.CutPrefix(events.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var td struct{ Status Status }
+			if err := json.Unmarshal([]byte(data), &td); err != nil {
+				t.Fatalf("json.Unmarshal(%s) failed: %v", data, err)
+			}
+			return td.Status
+		}
+		t.Fatalf("Stream ended: %v", events.Err())
+		return StatusUnknown
+	}
+	if s := status(); s != StatusOpen {
+		t.Errorf("Expected open on connect, got %s", s)
+	}
+
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	r, err := http.Get(server + "/")
+	if err != nil {
+		t.Fatalf("http.Get(/) failed: %v", err)
+	}
+	r.Body.Close()
+	if s := status(); s != StatusClosed {
+		t.Errorf("Expected closed after the change, got %s", s)
+	}
+}
+
+// TestEventsSeeded checks that a stream opened once the status is known
+// starts from the hub rather than fetching the feed.
+func TestEventsSeeded(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	server := startTestServer(t, h)
+	r, err := http.Get(server + "/")
+	if err != nil {
+		t.Fatalf("http.Get(/) failed: %v", err)
+	}
+	r.Body.Close()
+
+	resp, err := http.Get(server + "/events")
+	if err != nil {
+		t.Fatalf("http.Get(/events) failed: %v", err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewScanner(resp.Body)
+	for events.Scan() {
+		if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+			if !strings.Contains(data, `"status":"closed"`) {
+				t.Errorf("Expected the closed status, got %s", data)
+			}
+			break
+		}
+	}
+	if n := fp.fetches(); n != 1 {
+		t.Errorf("Expected only the page view to fetch the feed, got %d fetches", n)
+	}
+}
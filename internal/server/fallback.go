@@ -0,0 +1,72 @@
+package server
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+var (
+	itemRE    = regexp.MustCompile(`(?is)<item\b[^>]*>(.*?)</item>`)
+	titleRE   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title>`)
+	linkRE    = regexp.MustCompile(`(?is)<link\b[^>]*>(.*?)</link>`)
+	descRE    = regexp.MustCompile(`(?is)<description\b[^>]*>(.*?)</description>`)
+	pubDateRE = regexp.MustCompile(`(?is)<pubDate\b[^>]*>(.*?)</pubDate>`)
+	cdataRE   = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+)
+
+// pubDateLayouts are the date formats tried for pubDate elements.
+var pubDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+}
+
+// lenientItems extracts RSS items from a document that failed to parse as
+// XML. The KC endpoint occasionally emits invalid XML (e.g. unescaped
+// ampersands), but the item elements themselves are usually intact enough
+// to pick out titles, links, and dates with regular expressions. This is a
+// last resort; items without a title are dropped.
+func lenientItems(doc []byte) []*gofeed.Item {
+	var items []*gofeed.Item
+	for _, m := range itemRE.FindAllSubmatch(doc, -1) {
+		body := m[1]
+		item := &gofeed.Item{
+			Title:       element(titleRE, body),
+			Link:        element(linkRE, body),
+			Description: element(descRE, body),
+		}
+		if item.Title == "" {
+			continue
+		}
+		if d := element(pubDateRE, body); d != "" {
+			for _, layout := range pubDateLayouts {
+				if t, err := time.Parse(layout, d); err == nil {
+					item.Published = d
+					item.PublishedParsed = &t
+					break
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// element returns the unescaped text of the first match of re in body.
+func element(re *regexp.Regexp, body []byte) string {
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	s := string(m[1])
+	if c := cdataRE.FindStringSubmatch(s); c != nil {
+		return strings.TrimSpace(c[1])
+	}
+	return strings.TrimSpace(html.UnescapeString(s))
+}
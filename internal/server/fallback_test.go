@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestLenientItems(t *testing.T) {
+	doc := []byte(`<?xml version="1.0"?>
+<rss><channel><title>Road Alerts & Closures</title>
+<item>
+  <title>Closed - NE 124th St & W Snoqualmie Valley Rd</title>
+  <link>https://example.com/a?x=1&y=2</link>
+  <description><![CDATA[<p>Flooding</p>]]></description>
+  <pubDate>Mon, 15 Nov 2021 03:41:00 -0800</pubDate>
+</item>
+<item><link>https://example.com/untitled</link></item>
+<item><title>Open - Tolt Hill Rd</title><pubDate>garbage</pubDate></item>
+</channel></rss>`)
+
+	items := lenientItems(doc)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if want := "Closed - NE 124th St & W Snoqualmie Valley Rd"; items[0].Title != want {
+		t.Errorf("Expected title %q, got %q", want, items[0].Title)
+	}
+	if want := "https://example.com/a?x=1&y=2"; items[0].Link != want {
+		t.Errorf("Expected link %q, got %q", want, items[0].Link)
+	}
+	if items[0].Description != "<p>Flooding</p>" {
+		t.Errorf("Expected CDATA description, got %q", items[0].Description)
+	}
+	if items[0].PublishedParsed == nil || items[0].PublishedParsed.Hour() != 3 {
+		t.Errorf("Expected parsed pubDate, got %v", items[0].PublishedParsed)
+	}
+	if items[1].PublishedParsed != nil {
+		t.Errorf("Expected unparseable pubDate to be dropped, got %v", items[1].PublishedParsed)
+	}
+}
+
+func TestFeedProviderMalformed(t *testing.T) {
+	feed := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<rss><channel><item><title>Closed - 124th & Tolt</title></item></channel>`)
+	}))
+	alerts, err := NewFeedProvider(DefaultKeywords, feed).Alerts(context.Background())
+	if err != nil {
+		t.Fatalf("Alerts failed: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Status != StatusClosed {
+		t.Errorf("Expected one closed alert, got %+v", alerts)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// maxFeedItems is the number of changes published in /feed.xml.
+const maxFeedItems = 50
+
+// baseURL returns the scheme and host the request was made to.
+func baseURL(r *http.Request) string {
+	return scheme(r) + "://" + r.Host
+}
+
+// changeTitle describes a change for people, e.g. "124th is closed".
+func changeTitle(c Change) string {
+	return fmt.Sprintf("%s is %s", c.Road, c.To)
+}
+
+// feed publishes an RSS feed with an item for every status change this
+// server has observed, newest first.
+func (h *handler) feed(w http.ResponseWriter, r *http.Request) {
+	base := baseURL(r)
+	f := &feeds.Feed{
+		Title:       fmt.Sprintf("Is %s Open!?", h.road),
+		Link:        &feeds.Link{Href: base + "/"},
+		Description: fmt.Sprintf("Status changes for %s", h.road),
+	}
+	changes := h.tracker.changesSince(time.Time{})
+	if len(changes) > maxFeedItems {
+		changes = changes[len(changes)-maxFeedItems:]
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		desc := []string{fmt.Sprintf("Changed from %s to %s.", c.From, c.To)}
+		if c.Reason != ReasonNone {
+			desc = append(desc, fmt.Sprintf("Reason: %s.", c.Reason))
+		}
+		if c.Detail != "" {
+			desc = append(desc, c.Detail)
+		}
+		f.Items = append(f.Items, &feeds.Item{
+			Id:          fmt.Sprintf("%s/feed.xml#%d", base, c.At.UnixNano()),
+			Title:       changeTitle(c),
+			Link:        &feeds.Link{Href: base + "/"},
+			Description: strings.Join(desc, " "),
+			Created:     c.At,
+		})
+	}
+	if len(changes) > 0 {
+		f.Updated = changes[len(changes)-1].At
+	}
+	rss, err := f.ToRss()
+	if err != nil {
+		h.internalError(w, r, "internal error: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	fmt.Fprint(w, rss)
+}
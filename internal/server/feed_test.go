@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestFeed(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	get("/")
+	fp.set(&Alert{Status: StatusClosed, Reason: ReasonFlooding, Title: "Closed - 124th"})
+	get("/")
+	fp.set()
+	get("/")
+
+	feed, err := gofeed.NewParser().Parse(strings.NewReader(get("/feed.xml").Body.String()))
+	if err != nil {
+		t.Fatalf("Failed to parse feed: %v", err)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(feed.Items))
+	}
+	if got := feed.Items[0].Title; got != "124th is open" {
+		t.Errorf("Expected newest item first, got %q", got)
+	}
+	if got := feed.Items[1].Description; !strings.Contains(got, "Reason: flooding") {
+		t.Errorf("Expected reason in description, got %q", got)
+	}
+}
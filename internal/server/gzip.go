@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipWriters recycles compressors, which are expensive to allocate.
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// compressible reports whether a response of the given Content-Type is
+// worth compressing. Camera images are already compressed, and event
+// streams have to reach the client as soon as they're written.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mt == "text/event-stream" {
+		return false
+	}
+	return strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "json") || strings.HasSuffix(mt, "xml")
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(enc, ";")
+			name = strings.TrimSpace(name)
+			if name != "gzip" && name != "*" {
+				continue
+			}
+			if k, q, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(q), 64); err == nil && f == 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter compresses text responses on the fly for clients that accept
+// gzip. Whether to compress is decided when the header is written, once the
+// handler has set the Content-Type.
+type gzipWriter struct {
+	http.ResponseWriter
+	accepts     bool
+	wroteHeader bool
+	gz          *gzip.Writer
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	hdr := g.Header()
+	if compressible(hdr.Get("Content-Type")) {
+		hdr.Add("Vary", "Accept-Encoding")
+		if g.accepts && code == http.StatusOK && hdr.Get("Content-Encoding") == "" {
+			hdr.Set("Content-Encoding", "gzip")
+			hdr.Del("Content-Length")
+			// The compressed body is a different representation, so
+			// its ETag can only be weak. If-None-Match still matches it.
+			if etag := hdr.Get("ETag"); strings.HasPrefix(etag, `"`) {
+				hdr.Set("ETag", "W/"+etag)
+			}
+			g.gz = gzipWriters.Get().(*gzip.Writer)
+			g.gz.Reset(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+// Flush sends whatever has been compressed so far.
+func (g *gzipWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (g *gzipWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// Hijack lets WebSocket handlers take over the connection.
+func (g *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(g.ResponseWriter).Hijack()
+}
+
+// close finishes the compressed stream, if any.
+func (g *gzipWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	g.gz.Reset(io.Discard)
+	gzipWriters.Put(g.gz)
+	g.gz = nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.8", true},
+		{"br", false},
+		{"gzip;q=0", false},
+		{"*", true},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", tc.header)
+		if got := acceptsGzip(r); got != tc.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path, encoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, path := range []string{"/", "/api/v1/status"} {
+		plain := get(path, "")
+		if plain.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected an uncompressed response without Accept-Encoding", path)
+		}
+		rec := get(path, "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("%s: expected gzip, got %q", path, got)
+		}
+		if got := strings.Join(rec.Header().Values("Vary"), ", "); !strings.Contains(got, "Accept-Encoding") {
+			t.Errorf("%s: expected Vary: Accept-Encoding, got %q", path, got)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("%s: gzip.NewReader failed: %v", path, err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: reading compressed body failed: %v", path, err)
+		}
+		if string(body) != plain.Body.String() {
+			t.Errorf("%s: decompressed body differs from the plain one", path)
+		}
+		if etag := rec.Header().Get("ETag"); etag != "W/"+plain.Header().Get("ETag") {
+			t.Errorf("%s: expected a weak ETag, got %q", path, etag)
+		}
+
+		// The weak ETag still lets the client revalidate.
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		r.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for a matching weak ETag, got %d", path, rec.Code)
+		}
+	}
+
+	if rec := get("/favicon.ico", "gzip"); rec.Header().Get("Content-Encoding") != "" {
+		t.Error("Expected images to be left uncompressed")
+	}
+}
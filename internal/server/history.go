@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// statusHistory is the response of /api/v1/history.
+type statusHistory struct {
+	// ObservedSince is when this server started watching the road. The
+	// feed keeps no history, so nothing earlier is known.
+	ObservedSince time.Time `json:"observedSince"`
+	Changes       []Change  `json:"changes"`
+}
+
+// history serves the status changes this server has observed as JSON,
+// oldest first. The optional "since" query parameter (RFC 3339) limits it to
+// changes at or after that time.
+func (h *handler) history(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.FormValue("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	sh := &statusHistory{
+		ObservedSince: h.tracker.stats().ObservedSince,
+		Changes:       h.tracker.changesSince(since),
+	}
+	if sh.Changes == nil {
+		sh.Changes = []Change{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sh)
+}
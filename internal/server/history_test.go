@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	history := func(path string) *statusHistory {
+		t.Helper()
+		var sh statusHistory
+		if err := json.Unmarshal(get(path).Body.Bytes(), &sh); err != nil {
+			t.Fatalf("json.Unmarshal failed: %v", err)
+		}
+		return &sh
+	}
+
+	get("/")
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	get("/")
+	mid := time.Now()
+	fp.set()
+	get("/")
+
+	sh := history("/api/v1/history")
+	if len(sh.Changes) != 2 || sh.ObservedSince.IsZero() {
+		t.Fatalf("Expected 2 changes, got %+v", sh)
+	}
+	if c := sh.Changes[0]; c.To != StatusClosed || c.Source != "Fake County" {
+		t.Errorf("Expected closure from Fake County first, got %+v", c)
+	}
+	sh = history("/api/v1/history?since=" + url.QueryEscape(mid.Format(time.RFC3339Nano)))
+	if len(sh.Changes) != 1 || sh.Changes[0].To != StatusOpen {
+		t.Errorf("Expected only the reopening, got %+v", sh.Changes)
+	}
+	if rec := get("/api/v1/history?since=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad since, got %d", rec.Code)
+	}
+}
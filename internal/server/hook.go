@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Change is an observed transition of the road's status.
+type Change struct {
+	Road   string `json:"road"`
+	From   Status `json:"from"`
+	To     Status `json:"to"`
+	Reason Reason `json:"reason,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	Link   string `json:"link,omitempty"`
+	// Source names where the new status came from: the provider, or
+	// "override" for a manual override.
+	Source string `json:"source,omitempty"`
+	// The change happened some time between After, when the old status was
+	// last observed, and At, when the new one was first observed. Feeds
+	// rarely say when a road actually reopened, so this range is the best
+	// estimate available.
+	After time.Time `json:"after"`
+	At    time.Time `json:"at"`
+}
+
+// Env describes the change as environment variables: FLOOD_ROAD,
+// FLOOD_FROM, FLOOD_TO, FLOOD_REASON, FLOOD_DETAIL, FLOOD_LINK,
+// FLOOD_SOURCE, FLOOD_AFTER, and FLOOD_AT (RFC 3339).
+func (c Change) Env() []string {
+	return []string{
+		"FLOOD_ROAD=" + c.Road,
+		"FLOOD_FROM=" + c.From.String(),
+		"FLOOD_TO=" + c.To.String(),
+		"FLOOD_REASON=" + string(c.Reason),
+		"FLOOD_DETAIL=" + c.Detail,
+		"FLOOD_LINK=" + c.Link,
+		"FLOOD_SOURCE=" + c.Source,
+		"FLOOD_AFTER=" + c.After.Format(time.RFC3339),
+		"FLOOD_AT=" + c.At.Format(time.RFC3339),
+	}
+}
+
+const (
+	// hookTimeout bounds each run of an ExecHook command.
+	hookTimeout = 30 * time.Second
+	// notifyQueue is how many changes may wait for slow hooks before
+	// new ones are dropped.
+	notifyQueue = 64
+)
+
+// ExecHook returns an OnChange hook that runs the named program with the
+// given arguments for each change. The Change is passed as JSON on stdin and
+// as FLOOD_* environment variables (see Env), covering integrations the
+// server will never build natively. The program is killed if it runs longer
+// than 30 seconds. Failures are logged.
+func ExecHook(name string, args ...string) func(Change) {
+	return execHook(hookTimeout, name, args...)
+}
+
+func execHook(timeout time.Duration, name string, args ...string) func(Change) {
+	return func(c Change) {
+		b, err := json.Marshal(c)
+		if err != nil {
+			log.Printf("Failed to marshal change: %v", err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdin = bytes.NewReader(b)
+		cmd.Env = append(os.Environ(), c.Env()...)
+		// Don't wait forever on output from children that outlive it.
+		cmd.WaitDelay = time.Second
+		if out, err := cmd.CombinedOutput(); err != nil {
+			log.Printf("On-change hook %s failed: %v: %s", name, err, out)
+		}
+	}
+}
+
+// notifier delivers changes to an OnChange hook from a single goroutine, one
+// at a time and in the order they were observed. If window is non-zero,
+// changes within window of the first are merged into one, so a status that
+// flaps (e.g. while mirrors disagree) produces a single notification of the
+// net change, or none if it ends up where it started.
+type notifier struct {
+	mu      sync.Mutex
+	window  time.Duration
+	hook    func(Change)
+	pending *Change
+
+	start sync.Once
+	queue chan Change
+}
+
+// notify queues c for delivery. It never blocks on the hook.
+func (n *notifier) notify(c Change) {
+	if n.window == 0 {
+		n.deliver(c)
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pending != nil {
+		n.pending.To = c.To
+		n.pending.Reason = c.Reason
+		n.pending.Detail = c.Detail
+		n.pending.Link = c.Link
+		n.pending.Source = c.Source
+		n.pending.At = c.At
+		// n.pending.After stays that of the first change.
+		return
+	}
+	n.pending = &c
+	time.AfterFunc(n.window, n.flush)
+}
+
+// flush delivers the pending change, if it is still a change.
+func (n *notifier) flush() {
+	n.mu.Lock()
+	c := n.pending
+	n.pending = nil
+	n.mu.Unlock()
+	if c != nil && c.From != c.To {
+		n.deliver(*c)
+	}
+}
+
+// deliver queues c for the delivery goroutine, starting it if needed. If
+// the hooks are so slow that the queue is full, c is dropped and logged
+// rather than blocking the request that observed it.
+func (n *notifier) deliver(c Change) {
+	n.start.Do(func() {
+		n.queue = make(chan Change, notifyQueue)
+		go func() {
+			for c := range n.queue {
+				n.hook(c)
+			}
+		}()
+	})
+	select {
+	case n.queue <- c:
+	default:
+		log.Printf("Dropped notification of %s going %s: hooks are backed up", c.Road, c.To)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "change.json")
+	hook := ExecHook("sh", "-c", `cat > "$0"`, out)
+	want := Change{
+		Road: "124th",
+		From: StatusOpen,
+		To:   StatusClosed,
+		At:   time.Date(2021, 11, 15, 3, 41, 0, 0, time.UTC),
+	}
+	hook(want)
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Hook did not write stdin: %v", err)
+	}
+	var got Change
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", b, err)
+	}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestExecHookEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	hook := ExecHook("sh", "-c", `echo "$FLOOD_ROAD $FLOOD_FROM $FLOOD_TO $FLOOD_AT" > "$0"`, out)
+	hook(Change{
+		Road: "124th",
+		From: StatusOpen,
+		To:   StatusClosed,
+		At:   time.Date(2021, 11, 15, 3, 41, 0, 0, time.UTC),
+	})
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Hook did not run: %v", err)
+	}
+	if got, want := string(b), "124th open closed 2021-11-15T03:41:00Z\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestExecHookTimeout(t *testing.T) {
+	hook := execHook(100*time.Millisecond, "sleep", "10")
+	start := time.Now()
+	hook(Change{Road: "124th"})
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Expected the hung hook to be killed, but it ran for %s", d)
+	}
+}
+
+func TestNotifierOrder(t *testing.T) {
+	got := make(chan Change, 10)
+	n := &notifier{hook: func(c Change) {
+		// A slow hook mustn't let later changes overtake earlier ones.
+		time.Sleep(time.Millisecond)
+		got <- c
+	}}
+	statuses := []Status{StatusAdvisory, StatusPartial, StatusClosed, StatusPartial, StatusOpen}
+	from := StatusOpen
+	for _, s := range statuses {
+		n.notify(Change{From: from, To: s})
+		from = s
+	}
+	for i, want := range statuses {
+		select {
+		case c := <-got:
+			if c.To != want {
+				t.Errorf("Notification %d: expected %s, got %s", i, want, c.To)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected notification %d", i)
+		}
+	}
+}
+
+func TestNotifierCoalesces(t *testing.T) {
+	got := make(chan Change, 10)
+	n := &notifier{window: 50 * time.Millisecond, hook: func(c Change) { got <- c }}
+
+	n.notify(Change{From: StatusOpen, To: StatusAdvisory})
+	n.notify(Change{From: StatusAdvisory, To: StatusClosed, Detail: "Closed - 124th"})
+	select {
+	case c := <-got:
+		if c.From != StatusOpen || c.To != StatusClosed || c.Detail != "Closed - 124th" {
+			t.Errorf("Expected merged open -> closed, got %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a coalesced notification")
+	}
+
+	// A flap back to the original status is not a change at all.
+	n.notify(Change{From: StatusClosed, To: StatusOpen})
+	n.notify(Change{From: StatusOpen, To: StatusClosed})
+	select {
+	case c := <-got:
+		t.Errorf("Expected no notification for a flap, got %+v", c)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxStreams is the default limit on concurrent /events and /ws
+	// connections.
+	DefaultMaxStreams = 1000
+	// streamBuffer is how many updates may queue for a stream client
+	// before it's considered stuck and evicted. Updates are rare, so a
+	// client that falls this far behind isn't reading at all.
+	streamBuffer = 4
+	// streamWriteTimeout bounds each write to a stream client, so a client
+	// that stops reading can't hold its goroutine forever.
+	streamWriteTimeout = 30 * time.Second
+)
+
+// subscriber receives status updates from a hub. Its channel is closed if
+// it's evicted for falling behind.
+type subscriber struct {
+	updates chan []byte
+}
+
+// hub fans status updates out to the /events and /ws clients. Each update
+// is marshaled once, by whichever request observed the change, rather than
+// once per client.
+type hub struct {
+	mu      sync.Mutex
+	max     int
+	clients map[*subscriber]struct{}
+	last    []byte
+	at      time.Time
+}
+
+func newHub(max int) *hub {
+	if max == 0 {
+		max = DefaultMaxStreams
+	}
+	return &hub{max: max, clients: make(map[*subscriber]struct{})}
+}
+
+// subscribe adds a client, or returns false if the hub is full. The last
+// update published, if any, is queued for the client straight away, so it
+// needn't compute the status itself.
+func (hb *hub) subscribe() (*subscriber, bool) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if len(hb.clients) >= hb.max {
+		return nil, false
+	}
+	s := &subscriber{updates: make(chan []byte, streamBuffer)}
+	if hb.last != nil {
+		s.updates <- hb.last
+	}
+	hb.clients[s] = struct{}{}
+	return s, true
+}
+
+// seeded reports whether anything has been published yet.
+func (hb *hub) seeded() bool {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return hb.last != nil
+}
+
+// unsubscribe removes a client, if it hasn't already been evicted.
+func (hb *hub) unsubscribe(s *subscriber) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	delete(hb.clients, s)
+}
+
+// publish queues an update, the status as fetched at at, for every client,
+// evicting those whose buffers are full. Concurrent requests can publish out
+// of order, so an update fetched before the last one is dropped, as is an
+// update identical to it.
+func (hb *hub) publish(update []byte, at time.Time) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	if at.Before(hb.at) || bytes.Equal(update, hb.last) {
+		return
+	}
+	hb.last = update
+	hb.at = at
+	for s := range hb.clients {
+		select {
+		case s.updates <- update:
+		default:
+			delete(hb.clients, s)
+			close(s.updates)
+		}
+	}
+}
+
+// size returns the number of connected clients.
+func (hb *hub) size() int {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return len(hb.clients)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHub(t *testing.T) {
+	hb := newHub(2)
+	a, ok := hb.subscribe()
+	if !ok {
+		t.Fatal("Expected the first subscriber to be accepted")
+	}
+	b, _ := hb.subscribe()
+	if _, ok := hb.subscribe(); ok {
+		t.Error("Expected a subscriber over the limit to be refused")
+	}
+
+	at := time.Now()
+	hb.publish([]byte("1"), at)
+	hb.publish([]byte("1"), at)
+	if got := string(<-a.updates); got != "1" {
+		t.Errorf("Expected the update, got %q", got)
+	}
+	if len(a.updates) != 0 {
+		t.Error("Expected a repeated update to be dropped")
+	}
+
+	// b never reads, so it's evicted once its buffer is full; a keeps up.
+	for i := 2; i <= streamBuffer+2; i++ {
+		hb.publish([]byte{byte('0' + i)}, at)
+		<-a.updates
+	}
+	for range b.updates {
+	}
+	if got := hb.size(); got != 1 {
+		t.Errorf("Expected 1 client after eviction, got %d", got)
+	}
+	// An update fetched before the last one published is out of date.
+	hb.publish([]byte("0"), at.Add(-time.Second))
+	if len(a.updates) != 0 {
+		t.Error("Expected an out-of-order update to be dropped")
+	}
+	hb.unsubscribe(b)
+	hb.unsubscribe(a)
+	if got := hb.size(); got != 0 {
+		t.Errorf("Expected no clients, got %d", got)
+	}
+}
+
+func TestStreamLimit(t *testing.T) {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th", MaxStreams: 1})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	sub, _ := h.(*handler).hub.subscribe()
+	defer h.(*handler).hub.unsubscribe(sub)
+	for _, path := range []string{"/events", "/ws"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503 over the limit, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// closure is a period when the road was closed. End is zero if the road is
+// still closed. Otherwise the road reopened some time between EndAfter and
+// End.
+type closure struct {
+	Start, End time.Time
+	EndAfter   time.Time
+	Reason     Reason
+	Detail     string
+}
+
+// closures returns the closures observed by the tracker, oldest first.
+func (t *tracker) closures() []closure {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observed {
+		return nil
+	}
+	var cs []closure
+	var cur *closure
+	if len(t.history) > 0 && t.history[0].From == StatusClosed ||
+		len(t.history) == 0 && t.status == StatusClosed {
+		cur = &closure{Start: t.first}
+	}
+	for _, c := range t.history {
+		switch {
+		case c.To == StatusClosed && cur == nil:
+			cur = &closure{Start: c.At, Reason: c.Reason, Detail: c.Detail}
+		case c.To != StatusClosed && cur != nil:
+			cur.End = c.At
+			cur.EndAfter = c.After
+			cs = append(cs, *cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		cs = append(cs, *cur)
+	}
+	return cs
+}
+
+// icsTime formats t as an iCalendar UTC date-time.
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// icsText escapes s for use in an iCalendar TEXT value.
+var icsText = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace
+
+// icsLine writes an iCalendar content line, folded at 75 octets as RFC 5545
+// requires.
+func icsLine(b *strings.Builder, line string) {
+	// Continuation lines start with a space, which counts toward the 75.
+	max := 75
+	for len(line) > max {
+		// Don't split a multi-byte character.
+		n := max
+		for n > 0 && line[n]&0xc0 == 0x80 {
+			n--
+		}
+		b.WriteString(line[:n] + "\r\n ")
+		line = line[n:]
+		max = 74
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// calendar publishes each closure as an iCalendar event, so closures show up
+// in calendar apps that subscribe to it. A closure that is still ongoing ends
+// now, and grows each time the calendar is refreshed.
+func (h *handler) calendar(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	var b strings.Builder
+	icsLine(&b, "BEGIN:VCALENDAR")
+	icsLine(&b, "VERSION:2.0")
+	icsLine(&b, "PRODID:-//jdtw.dev//flood//EN")
+	icsLine(&b, "X-WR-CALNAME:"+icsText(h.road+" closures"))
+	for _, c := range h.tracker.closures() {
+		end := c.End
+		if end.IsZero() {
+			end = now
+		}
+		summary := h.road + " closed"
+		if c.Reason != ReasonNone {
+			summary += " (" + string(c.Reason) + ")"
+		}
+		icsLine(&b, "BEGIN:VEVENT")
+		icsLine(&b, fmt.Sprintf("UID:%d-closure@%s", c.Start.UnixNano(), r.Host))
+		icsLine(&b, "DTSTAMP:"+icsTime(now))
+		icsLine(&b, "DTSTART:"+icsTime(c.Start))
+		icsLine(&b, "DTEND:"+icsTime(end))
+		icsLine(&b, "SUMMARY:"+icsText(summary))
+		var desc []string
+		if c.Detail != "" {
+			desc = append(desc, c.Detail)
+		}
+		if !c.EndAfter.IsZero() && c.End.Sub(c.EndAfter) >= time.Minute {
+			desc = append(desc, fmt.Sprintf("Reopened between %s and %s.",
+				c.EndAfter.In(h.loc).Format("3:04 PM"), c.End.In(h.loc).Format("3:04 PM on Mon, Jan 2")))
+		}
+		if desc != nil {
+			icsLine(&b, "DESCRIPTION:"+icsText(strings.Join(desc, "\n")))
+		}
+		icsLine(&b, "URL:"+baseURL(r)+"/")
+		icsLine(&b, "END:VEVENT")
+	}
+	icsLine(&b, "END:VCALENDAR")
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	fmt.Fprint(w, b.String())
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	get("/")
+	fp.set(&Alert{Status: StatusClosed, Reason: ReasonFlooding, Title: "Closed - 124th"})
+	get("/")
+	fp.set()
+	get("/")
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	get("/")
+
+	rec := get("/closures.ics")
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/calendar") {
+		t.Errorf("Expected text/calendar, got %q", got)
+	}
+	body := rec.Body.String()
+	if n := strings.Count(body, "BEGIN:VEVENT\r\n"); n != 2 {
+		t.Errorf("Expected 2 events, got %d:\n%s", n, body)
+	}
+	if !strings.Contains(body, "SUMMARY:124th closed (flooding)\r\n") {
+		t.Errorf("Expected summary with reason, got:\n%s", body)
+	}
+	if !strings.Contains(body, "DESCRIPTION:Closed - 124th\r\n") {
+		t.Errorf("Expected detail in description, got:\n%s", body)
+	}
+}
+
+func TestICSLineFolding(t *testing.T) {
+	for _, tt := range []struct {
+		line  string
+		folds int
+	}{
+		{"DESCRIPTION:" + strings.Repeat("é", 50), 1},
+		// 75 octets, then 74 after each leading space.
+		{"DESCRIPTION:" + strings.Repeat("x", 250), 3},
+	} {
+		var b strings.Builder
+		icsLine(&b, tt.line)
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+		for _, l := range lines {
+			if len(l) > 75 {
+				t.Errorf("Line is %d octets: %q", len(l), l)
+			}
+		}
+		if len(lines)-1 != tt.folds {
+			t.Errorf("Expected %d folds, got %d: %q", tt.folds, len(lines)-1, lines)
+		}
+		if got := strings.ReplaceAll(b.String(), "\r\n ", ""); got != tt.line+"\r\n" {
+			t.Errorf("Unfolded line doesn't match: %q", got)
+		}
+	}
+}
+
+func TestClosureReopenRange(t *testing.T) {
+	now := time.Date(2021, 11, 15, 14, 0, 0, 0, time.UTC)
+	tr := newTracker()
+	tr.now = func() time.Time { return now }
+	tr.observe(Change{To: StatusClosed}, now)
+	now = now.Add(10 * time.Minute)
+	tr.observe(Change{To: StatusClosed}, now)
+	now = now.Add(5 * time.Minute)
+	tr.observe(Change{To: StatusOpen}, now)
+
+	cs := tr.closures()
+	if len(cs) != 1 {
+		t.Fatalf("Expected one closure, got %+v", cs)
+	}
+	if want := now.Add(-5 * time.Minute); !cs[0].EndAfter.Equal(want) || !cs[0].End.Equal(now) {
+		t.Errorf("Expected reopen between %v and %v, got %+v", want, now, cs[0])
+	}
+}
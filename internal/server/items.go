@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// feedItem is an alert as the server sees it, for debugging matches.
+type feedItem struct {
+	Title string `json:"title"`
+	// Scope is the clause of a multi-road title that roads are matched
+	// against, if the title was split.
+	Scope     string     `json:"scope,omitempty"`
+	Status    Status     `json:"status"`
+	Reason    Reason     `json:"reason,omitempty"`
+	Link      string     `json:"link,omitempty"`
+	Published *time.Time `json:"published,omitempty"`
+	// Matches names the segments the alert mentions, and Decides those
+	// whose status it determines, being the latest to mention them.
+	Matches []string `json:"matches,omitempty"`
+	Decides []string `json:"decides,omitempty"`
+}
+
+// feedSnapshot is the response of /api/v1/feed.
+type feedSnapshot struct {
+	Source   string     `json:"source,omitempty"`
+	Segments []Segment  `json:"segments"`
+	Items    []feedItem `json:"items"`
+}
+
+// feedItems serves the provider's current alerts as parsed and graded, with
+// the segments each one matches, so that users can see why an alert did or
+// didn't apply to their road.
+func (h *handler) feedItems(w http.ResponseWriter, r *http.Request) {
+	alerts, err := h.alerts(r)
+	if err != nil {
+		h.unavailableJSON(w, r, err)
+		return
+	}
+	decides := make(map[*Alert][]string)
+	for _, seg := range h.segments {
+		if a := deciding(seg, alerts); a != nil {
+			decides[a] = append(decides[a], seg.Name)
+		}
+	}
+	fs := &feedSnapshot{Segments: h.segments, Items: []feedItem{}}
+	fs.Source, _ = h.provider.Source()
+	for _, a := range alerts {
+		item := feedItem{
+			Title:     a.Title,
+			Scope:     a.Scope,
+			Status:    a.Status,
+			Reason:    a.Reason,
+			Link:      a.Link,
+			Published: a.Published,
+			Decides:   decides[a],
+		}
+		for _, seg := range h.segments {
+			if seg.matches(a) {
+				item.Matches = append(item.Matches, seg.Name)
+			}
+		}
+		fs.Items = append(fs.Items, item)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fs)
+}
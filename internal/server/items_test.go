@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFeedItems(t *testing.T) {
+	older := time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{
+			{Status: StatusClosed, Title: "Closed - 124th west of SR203", Published: &older},
+			{Status: StatusOpen, Title: "Reopened - 124th west of SR203", Published: &newer},
+			{Status: StatusClosed, Title: "Closed - Tolt Hill Rd"},
+		}},
+		Road: "124th",
+		Segments: []Segment{
+			{Name: "West", Match: "west of SR203"},
+			{Name: "East", Match: "east of the river"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/feed", nil))
+	var fs feedSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &fs); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", rec.Body, err)
+	}
+	if fs.Source != "Fake County" || len(fs.Segments) != 2 || len(fs.Items) != 3 {
+		t.Fatalf("Unexpected snapshot: %+v", fs)
+	}
+	for i, want := range []struct {
+		matches, decides []string
+	}{
+		{[]string{"West"}, nil},
+		{[]string{"West"}, []string{"West"}},
+		{nil, nil},
+	} {
+		item := fs.Items[i]
+		if !slices.Equal(item.Matches, want.matches) || !slices.Equal(item.Decides, want.decides) {
+			t.Errorf("%q: expected matches %v and decides %v, got %v and %v", item.Title, want.matches, want.decides, item.Matches, item.Decides)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogFormat selects how the handler writes request and error logs.
+type LogFormat int
+
+const (
+	// TextLog writes plain lines through the standard logger.
+	TextLog LogFormat = iota
+	// GCPLog writes JSON entries that Cloud Logging parses into structured
+	// logs with severity, httpRequest, and trace fields.
+	GCPLog
+)
+
+// gcpEntry is a structured log entry as understood by Cloud Logging. See
+// https://cloud.google.com/logging/docs/structured-logging.
+type gcpEntry struct {
+	Severity    string          `json:"severity"`
+	Message     string          `json:"message"`
+	HTTPRequest *gcpHTTPRequest `json:"httpRequest,omitempty"`
+	Trace       string          `json:"logging.googleapis.com/trace,omitempty"`
+	SpanID      string          `json:"logging.googleapis.com/spanId,omitempty"`
+}
+
+// gcpHTTPRequest is the subset of the LogEntry HttpRequest message that we
+// populate.
+type gcpHTTPRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	Status        int    `json:"status,omitempty"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	Latency       string `json:"latency,omitempty"`
+}
+
+// entryMu serializes structured log entries. The standard logger (through
+// gcpWriter) and the handler's own entries both go to stderr, and must not
+// interleave.
+var entryMu sync.Mutex
+
+// gcpWriter wraps each line written to it in an INFO entry.
+type gcpWriter struct {
+	w io.Writer
+}
+
+// NewGCPWriter returns a writer suitable for log.SetOutput that wraps every
+// line from the standard logger in an INFO Cloud Logging entry. Pair it with
+// log.SetFlags(0); Cloud Logging timestamps entries itself.
+func NewGCPWriter(w io.Writer) io.Writer {
+	return &gcpWriter{w: w}
+}
+
+func (g *gcpWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	if err := writeEntry(g.w, &gcpEntry{Severity: "INFO", Message: msg}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeEntry marshals e and writes it to w as a single line.
+func writeEntry(w io.Writer, e *gcpEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	entryMu.Lock()
+	defer entryMu.Unlock()
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack lets WebSocket handlers take over the connection.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	s.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(s.ResponseWriter).Hijack()
+}
+
+// remoteAddr returns the client address, respecting the X-Forwarded-For
+// header to support running behind a proxy.
+func remoteAddr(r *http.Request) string {
+	if remote := strings.Join(r.Header["X-Forwarded-For"], " "); remote != "" {
+		return remote
+	}
+	return r.RemoteAddr
+}
+
+// gcpTrace extracts the trace and span IDs from the X-Cloud-Trace-Context
+// header ("TRACE_ID/SPAN_ID;o=1") and formats the trace for the given project.
+// The header's span ID is decimal, but log entries want 16 hex digits.
+func gcpTrace(r *http.Request, project string) (trace, span string) {
+	h := r.Header.Get("X-Cloud-Trace-Context")
+	if h == "" || project == "" {
+		return "", ""
+	}
+	id, rest, _ := strings.Cut(h, "/")
+	dec, _, _ := strings.Cut(rest, ";")
+	if n, err := strconv.ParseUint(dec, 10, 64); err == nil {
+		span = fmt.Sprintf("%016x", n)
+	}
+	return fmt.Sprintf("projects/%s/traces/%s", project, id), span
+}
+
+// logged logs the HTTP request in the handler's configured format.
+func (h *handler) logged(hf http.HandlerFunc) http.HandlerFunc {
+	if h.logFormat != GCPLog {
+		return func(w http.ResponseWriter, r *http.Request) {
+			log.Printf("%s %s %s %s", remoteAddr(r), r.Method, r.URL, r.UserAgent())
+			hf(w, r)
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		hf(rec, r)
+		trace, span := gcpTrace(r, h.gcpProject)
+		writeEntry(h.logOut, &gcpEntry{
+			Severity: "INFO",
+			Message:  fmt.Sprintf("%s %s", r.Method, r.URL),
+			HTTPRequest: &gcpHTTPRequest{
+				RequestMethod: r.Method,
+				RequestURL:    r.URL.String(),
+				Status:        rec.status,
+				UserAgent:     r.UserAgent(),
+				RemoteIP:      h.clientIP(r),
+				Latency:       fmt.Sprintf("%.9fs", time.Since(start).Seconds()),
+			},
+			Trace:  trace,
+			SpanID: span,
+		})
+	}
+}
+
+// warnf logs a warning for the operator.
+func (h *handler) warnf(r *http.Request, format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	if h.logFormat != GCPLog {
+		log.Print("WARNING: " + msg)
+		return
+	}
+	trace, span := gcpTrace(r, h.gcpProject)
+	writeEntry(h.logOut, &gcpEntry{
+		Severity: "WARNING",
+		Message:  msg,
+		Trace:    trace,
+		SpanID:   span,
+	})
+}
+
+// internalError responds with a 500 code and the given message.
+func (h *handler) internalError(w http.ResponseWriter, r *http.Request, format string, v ...interface{}) {
+	error := h.errorf(r, format, v...)
+	http.Error(w, error, http.StatusInternalServerError)
+}
+
+// errorf logs an error about the request and returns the message.
+func (h *handler) errorf(r *http.Request, format string, v ...interface{}) string {
+	error := fmt.Sprintf(format, v...)
+	if h.logFormat == GCPLog {
+		trace, span := gcpTrace(r, h.gcpProject)
+		writeEntry(h.logOut, &gcpEntry{
+			Severity: "ERROR",
+			Message:  error,
+			Trace:    trace,
+			SpanID:   span,
+		})
+	} else {
+		log.Print(error)
+	}
+	return error
+}
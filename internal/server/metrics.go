@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// metricsSnapshot is a small summary of the server's state for dashboards
+// and kiosks. It is read from what the server already knows, so fetching it
+// never polls the provider. Ages are in seconds, or -1 if unknown.
+type metricsSnapshot struct {
+	Status Status `json:"status"`
+	// StatusAgeSeconds is how long ago this server saw the status change.
+	StatusAgeSeconds int64 `json:"statusAgeSeconds"`
+	// FeedAgeSeconds is how long ago the provider was last polled
+	// successfully.
+	FeedAgeSeconds int64 `json:"feedAgeSeconds"`
+	// Alerts is how many alerts the last successful poll returned.
+	Alerts int `json:"alerts"`
+	// FeedFailures counts failed polls since the last successful one.
+	FeedFailures int `json:"feedFailures"`
+	// Changes counts the status changes observed, up to the history limit.
+	Changes int `json:"changes"`
+	// Streams is how many /events and /ws clients are connected.
+	Streams       int   `json:"streams"`
+	UptimeSeconds int64 `json:"uptimeSeconds"`
+}
+
+// age returns the seconds since t, or -1 if t is zero.
+func age(now, t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return int64(now.Sub(t) / time.Second)
+}
+
+// summary returns the current status, when it last changed, and how many
+// changes are in the history.
+func (t *tracker) summary() (Status, time.Time, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status, t.changed, len(t.history)
+}
+
+// metrics serves a metricsSnapshot as JSON.
+func (h *handler) metrics(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	m := &metricsSnapshot{UptimeSeconds: age(now, h.started)}
+	var changed, polled time.Time
+	m.Status, changed, m.Changes = h.tracker.summary()
+	m.StatusAgeSeconds = age(now, changed)
+	polled, m.Alerts, m.FeedFailures = h.outage.health()
+	m.FeedAgeSeconds = age(now, polled)
+	m.Streams = h.hub.size()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	metrics := func() *metricsSnapshot {
+		t.Helper()
+		var m metricsSnapshot
+		if err := json.Unmarshal(get("/api/v1/metrics").Body.Bytes(), &m); err != nil {
+			t.Fatalf("json.Unmarshal failed: %v", err)
+		}
+		return &m
+	}
+
+	if m := metrics(); m.FeedAgeSeconds != -1 || m.StatusAgeSeconds != -1 {
+		t.Errorf("Expected unknown ages before any poll, got %+v", m)
+	}
+	get("/")
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"}, &Alert{Status: StatusOpen, Title: "Open - Tolt Hill"})
+	get("/")
+	fp.fail(errors.New("connection refused"))
+	get("/")
+
+	m := metrics()
+	if m.Status != StatusClosed || m.Alerts != 2 || m.FeedFailures != 1 || m.Changes != 1 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+	if m.FeedAgeSeconds < 0 || m.StatusAgeSeconds < 0 {
+		t.Errorf("Expected known ages, got %+v", m)
+	}
+}
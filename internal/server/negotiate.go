@@ -0,0 +1,57 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate picks the media type in offers that the Accept header prefers.
+// Each offer's quality comes from the most specific media range matching it;
+// ties go to the earlier offer. If the header is empty or accepts none of
+// the offers, negotiate returns the first offer rather than failing, since
+// any representation is more useful than 406 Not Acceptable.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		if q := quality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// quality returns the q value that accept gives mediaType, or 0.
+func quality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range strings.Split(accept, ",") {
+		rng, params, _ := strings.Cut(r, ";")
+		rng = strings.ToLower(strings.TrimSpace(rng))
+		var s int
+		switch {
+		case rng == mediaType:
+			s = 2
+		case rng == typ+"/*":
+			s = 1
+		case rng == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		specificity, q = s, 1
+		for _, p := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(p, "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+	}
+	return q
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"text/html", "application/json", "text/plain"}
+	tests := []struct {
+		accept, want string
+	}{
+		{"", "text/html"},
+		{"*/*", "text/html"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"application/json", "application/json"},
+		{"application/json, text/plain;q=0.5", "application/json"},
+		{"text/plain", "text/plain"},
+		{"text/*;q=0.5, application/json;q=0.4", "text/html"},
+		{"text/html;q=0.1, text/plain", "text/plain"},
+		{"text/*, text/html;q=0", "text/plain"},
+		{"image/png", "text/html"},
+	}
+	for _, tc := range tests {
+		if got := negotiate(tc.accept, offers...); got != tc.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestRootNegotiation(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := get("application/json")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON, got %q", ct)
+	}
+	var td templateData
+	if err := json.Unmarshal(rec.Body.Bytes(), &td); err != nil || td.Status != StatusClosed {
+		t.Errorf("Expected the closed status as JSON, got %s (%v)", rec.Body, err)
+	}
+	if got := strings.Join(rec.Header().Values("Vary"), ", "); !strings.Contains(got, "Accept") {
+		t.Errorf("Expected Vary: Accept, got %q", got)
+	}
+
+	if rec := get("text/plain"); rec.Body.String() != "CLOSED\n" {
+		t.Errorf("Expected one word, got %q", rec.Body)
+	}
+	if rec := get("text/html"); !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected HTML, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	// Each representation has its own ETag.
+	if get("text/plain").Header().Get("ETag") == get("application/json").Header().Get("ETag") {
+		t.Error("Expected different ETags for different representations")
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// apiParam is a query parameter of a JSON endpoint.
+type apiParam struct {
+	name, description string
+	schema            map[string]interface{}
+}
+
+// apiEndpoint describes a JSON endpoint for the OpenAPI document. The
+// response schema is reflected from the type the handler encodes, so the
+// document can't drift from what's actually served. If the status can't be
+// determined, endpoints with an unavailable type answer 503 with it.
+type apiEndpoint struct {
+	path, id, summary string
+	response          reflect.Type
+	unavailable       reflect.Type
+	params            []apiParam
+}
+
+var apiEndpoints = []apiEndpoint{
+	{
+		path:        "/api/v1/status",
+		id:          "getStatus",
+		summary:     "Current road status",
+		response:    reflect.TypeOf(templateData{}),
+		unavailable: reflect.TypeOf(templateData{}),
+	},
+	{
+		path:        "/api/v1/device",
+		id:          "getDeviceStatus",
+		summary:     "Compact status for small devices, optionally long-polled",
+		response:    reflect.TypeOf(deviceStatus{}),
+		unavailable: reflect.TypeOf(deviceStatus{}),
+		params: []apiParam{
+			{"since", "Status number the device already has; the request is held until it changes", map[string]interface{}{"type": "integer"}},
+			{"wait", "Seconds to hold the request, at most 300 (default 60)", map[string]interface{}{"type": "integer"}},
+		},
+	},
+	{
+		path:     "/api/v1/metrics",
+		id:       "getMetrics",
+		summary:  "Server and feed health",
+		response: reflect.TypeOf(metricsSnapshot{}),
+	},
+	{
+		path:     "/api/v1/history",
+		id:       "getHistory",
+		summary:  "Status changes observed by this server, oldest first",
+		response: reflect.TypeOf(statusHistory{}),
+		params: []apiParam{
+			{"since", "Only include changes at or after this time", map[string]interface{}{"type": "string", "format": "date-time"}},
+		},
+	},
+	{
+		path:        "/api/v1/feed",
+		id:          "getFeed",
+		summary:     "Current alerts as parsed, with the segments each matches",
+		response:    reflect.TypeOf(feedSnapshot{}),
+		unavailable: reflect.TypeOf(templateData{}),
+	},
+	{
+		path:     "/stats",
+		id:       "getStats",
+		summary:  "Closure statistics over the observed period",
+		response: reflect.TypeOf(closureStats{}),
+	},
+}
+
+// openAPI returns the OpenAPI 3 document describing the JSON endpoints.
+func openAPI() map[string]interface{} {
+	paths := make(map[string]interface{})
+	for _, e := range apiEndpoints {
+		responses := map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": jsonSchema(e.response)},
+				},
+			},
+		}
+		if e.unavailable != nil {
+			responses["503"] = map[string]interface{}{
+				"description": "The status is unknown because the feed couldn't be fetched",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": jsonSchema(e.unavailable)},
+				},
+			}
+		}
+		op := map[string]interface{}{
+			"operationId": e.id,
+			"summary":     e.summary,
+			"responses":   responses,
+		}
+		if e.params != nil {
+			var params []interface{}
+			for _, p := range e.params {
+				params = append(params, map[string]interface{}{
+					"name":        p.name,
+					"in":          "query",
+					"description": p.description,
+					"schema":      p.schema,
+				})
+			}
+			op["parameters"] = params
+		}
+		paths[e.path] = map[string]interface{}{"get": op}
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   "flood",
+			"version": strconv.Itoa(SchemaVersion),
+		},
+		"paths": paths,
+	}
+}
+
+// openAPIDoc serves the OpenAPI document.
+func openAPIDoc(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openAPI())
+}
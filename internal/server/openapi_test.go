@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Get struct {
+				Responses map[string]struct {
+					Content map[string]struct {
+						Schema struct {
+							Properties map[string]json.RawMessage `json:"properties"`
+						} `json:"schema"`
+					} `json:"content"`
+				} `json:"responses"`
+			} `json:"get"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", rec.Body, err)
+	}
+	if doc.OpenAPI == "" || len(doc.Paths) != len(apiEndpoints) {
+		t.Fatalf("Expected an OpenAPI document with %d paths, got %s", len(apiEndpoints), rec.Body)
+	}
+
+	// Every documented endpoint is served, and every field it returns is
+	// documented.
+	for path, item := range doc.Paths {
+		props := item.Get.Responses["200"].Content["application/json"].Schema.Properties
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, rec.Code)
+			continue
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: json.Unmarshal(%s) failed: %v", path, rec.Body, err)
+			continue
+		}
+		for k := range body {
+			if _, ok := props[k]; !ok {
+				t.Errorf("%s: field %q is missing from the document", path, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// outage tracks consecutive provider failures, so that the last known status
+// can be shown while the upstream feed is unreachable.
+type outage struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	threshold int
+	failures  int
+	since     time.Time
+	last      *templateData
+	polled    time.Time
+	alerts    int
+}
+
+// newOutage returns an outage tracker that serves the last known status
+// after threshold consecutive failures. A zero threshold disables it.
+func newOutage(threshold int) *outage {
+	return &outage{now: time.Now, threshold: threshold}
+}
+
+// ok records a successful poll, its result, and how many alerts it returned.
+func (o *outage) ok(td *templateData, alerts int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.failures = 0
+	o.polled = o.now()
+	o.alerts = alerts
+	last := *td
+	o.last = &last
+}
+
+// fail records a failed poll. If the threshold has been reached and a status
+// is known, it returns a copy of the last known status and when the failures
+// began.
+func (o *outage) fail() (*templateData, time.Time, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failures == 0 {
+		o.since = o.now()
+	}
+	o.failures++
+	if o.threshold == 0 || o.failures < o.threshold || o.last == nil {
+		return nil, time.Time{}, false
+	}
+	td := *o.last
+	return &td, o.since, true
+}
+
+// health returns when the last successful poll was, how many alerts it
+// returned, and how many polls have failed since.
+func (o *outage) health() (polled time.Time, alerts, failures int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.polled, o.alerts, o.failures
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOutage(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", OutageThreshold: 2})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+	get()
+
+	fp.fail(errors.New("connection refused"))
+	if rec := get(); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected unknown status before the threshold, got %d", rec.Code)
+	}
+	rec := get()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected last known status, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Fake County feed unreachable since") {
+		t.Errorf("Expected outage banner, got:\n%s", body)
+	}
+	if !strings.Contains(body, "124th is Closed!") {
+		t.Errorf("Expected last known status, got:\n%s", body)
+	}
+
+	fp.fail(nil)
+	if body := get().Body.String(); strings.Contains(body, "unreachable") {
+		t.Errorf("Expected banner to clear on recovery, got:\n%s", body)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// OutboundPolicy restricts the URLs the server fetches, such as feeds, so
+// that a mistaken or malicious URL in its configuration can't be used to
+// reach internal services.
+type OutboundPolicy struct {
+	// Hosts, if set, are the only host names that may be fetched.
+	Hosts []string
+	// AllowPrivate permits connections to loopback, private, and
+	// link-local addresses, e.g. for a feed mirror on the local network.
+	AllowPrivate bool
+}
+
+// Check returns an error if u may not be fetched.
+func (p *OutboundPolicy) Check(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s: scheme must be http or https", u.Redacted())
+	}
+	if len(p.Hosts) == 0 {
+		return nil
+	}
+	for _, h := range p.Hosts {
+		if strings.EqualFold(h, u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: host %q is not allowed", u.Redacted(), u.Hostname())
+}
+
+// Client returns an HTTP client that enforces the policy on every request,
+// including redirects, and checks the address actually dialed, so that DNS
+// can't be used to reach a private address either.
+func (p *OutboundPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !p.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := ap.Addr().Unmap(); !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				return fmt.Errorf("connecting to %s is not allowed", ip)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return p.Check(req.URL)
+		},
+	}
+}
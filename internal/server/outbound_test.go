@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestOutboundPolicyCheck(t *testing.T) {
+	p := &OutboundPolicy{Hosts: []string{"gismaps.kingcounty.gov"}}
+	for _, tc := range []struct {
+		url string
+		ok  bool
+	}{
+		{"https://gismaps.kingcounty.gov/roadalert/rss.aspx", true},
+		{"https://GISMAPS.kingcounty.gov/", true},
+		{"https://example.com/feed", false},
+		{"file:///etc/passwd", false},
+		{"gopher://gismaps.kingcounty.gov/", false},
+	} {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatalf("url.Parse(%s) failed: %v", tc.url, err)
+		}
+		if err := p.Check(u); (err == nil) != tc.ok {
+			t.Errorf("Check(%s) = %v, expected ok=%t", tc.url, err, tc.ok)
+		}
+	}
+}
+
+func TestOutboundPolicyClient(t *testing.T) {
+	local := startTestServer(t, http.NotFoundHandler())
+
+	p := &OutboundPolicy{}
+	if _, err := p.Client(0).Get(local); err == nil {
+		t.Error("Expected a connection to loopback to be refused")
+	}
+
+	// Redirects are checked against the policy too.
+	redirect := startTestServer(t, http.RedirectHandler(local, http.StatusFound))
+	p = &OutboundPolicy{Hosts: []string{"127.0.0.1"}, AllowPrivate: true}
+	resp, err := p.Client(0).Get(redirect)
+	if err != nil {
+		t.Fatalf("Expected loopback to be allowed: %v", err)
+	}
+	resp.Body.Close()
+	p.Hosts = []string{"example.com"}
+	if _, err := p.Client(0).Get(redirect); err == nil {
+		t.Error("Expected a redirect to a disallowed host to be refused")
+	}
+}
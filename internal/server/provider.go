@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// KingCountyFeedURL is the King County road alert RSS feed.
+const KingCountyFeedURL = "https://gismaps.kingcounty.gov/roadalert/rss.aspx"
+
+// maxFeedSize bounds how much of a feed is read. The King County feed is a
+// few kilobytes.
+const maxFeedSize = 5 << 20
+
+// DefaultUserAgent identifies feed requests, with a link the feed's operator
+// can follow to find out who is polling them.
+const DefaultUserAgent = "flood (+https://github.com/jdtw/flood)"
+
+// Alert is a single road alert, as reported by a Provider.
+type Alert struct {
+	Status Status
+	Reason Reason
+	Title  string
+	// Scope, if set, is the part of the title this alert applies to, for
+	// feed items that cover several roads. Roads are matched against it
+	// instead of the title.
+	Scope string
+	// Description is the plain text of the alert's description or
+	// content, with any markup removed.
+	Description string
+	Link        string
+	Published   *time.Time
+}
+
+// scope returns the text that roads are matched against.
+func (a *Alert) scope() string {
+	if a.Scope != "" {
+		return a.Scope
+	}
+	return a.Title
+}
+
+// Provider is a regional source of road alerts.
+type Provider interface {
+	// Alerts returns the source's current alerts, graded. Callers must not
+	// rely on their order.
+	Alerts(ctx context.Context) ([]*Alert, error)
+	// Source names the data source and links to its human-readable page,
+	// for attribution on the status page.
+	Source() (name, link string)
+}
+
+// Keyword maps a phrase in an alert title to the status it indicates.
+type Keyword struct {
+	Phrase string
+	Status Status
+}
+
+// DefaultKeywords grades alerts using the wording seen in the King County
+// feed and common variants of it. Order matters: restricted closures are
+// often titled "Closed to through traffic", and "Reopened" must win over
+// later mentions of the closure.
+var DefaultKeywords = []Keyword{
+	{"one lane", StatusPartial},
+	{"flagger", StatusPartial},
+	{"local access only", StatusPartial},
+	{"through traffic", StatusPartial},
+	{"reopened", StatusOpen},
+	{"open to traffic", StatusOpen},
+	{"now open", StatusOpen},
+	{"closed", StatusClosed},
+	{"water over roadway", StatusAdvisory},
+	{"water on roadway", StatusAdvisory},
+	{"high water", StatusAdvisory},
+}
+
+// FeedProvider reads alerts from one or more RSS or Atom feeds, such as an
+// endpoint and its mirror. Items from every reachable feed are merged, so
+// alerts are still available if some of the feeds are down. Each item's
+// title is graded by the first Keyword whose phrase it contains, ignoring
+// case; items matching no keyword are considered open.
+type FeedProvider struct {
+	URLs     []string
+	Keywords []Keyword
+	Name     string
+	Link     string
+	// UserAgent is sent with feed requests. Include a contact URL or email
+	// address so the feed's operator can get in touch instead of blocking
+	// the server.
+	UserAgent string
+	// Client fetches the feeds. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NewFeedProvider returns a FeedProvider for the given keyword table and
+// feed URLs.
+func NewFeedProvider(keywords []Keyword, urls ...string) *FeedProvider {
+	p := &FeedProvider{
+		URLs:      urls,
+		Keywords:  keywords,
+		UserAgent: DefaultUserAgent,
+	}
+	if len(urls) > 0 {
+		p.Name = urls[0]
+		p.Link = urls[0]
+	}
+	return p
+}
+
+// KingCounty returns a Provider for the King County road alert feed.
+func KingCounty() *FeedProvider {
+	p := NewFeedProvider(DefaultKeywords, KingCountyFeedURL)
+	p.Name = "King County"
+	p.Link = "https://gismaps.kingcounty.gov/MyCommute/"
+	return p
+}
+
+// Alerts implements Provider. Items whose titles list several roads with
+// their own statuses ("Closed: Tolt Hill Rd; Reopened: NE 124th St") are
+// split into one alert per clause, so each road gets the status of the
+// clause that mentions it. An error is only returned if every feed fails.
+func (p *FeedProvider) Alerts(ctx context.Context) ([]*Alert, error) {
+	if len(p.URLs) == 0 {
+		return nil, errors.New("no feed URLs configured")
+	}
+	feeds := make([][]*gofeed.Item, len(p.URLs))
+	errs := make([]error, len(p.URLs))
+	var wg sync.WaitGroup
+	for i, url := range p.URLs {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			feeds[i], errs[i] = p.fetch(ctx, url)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", url, errs[i])
+			}
+		}(i, url)
+	}
+	wg.Wait()
+
+	var alerts []*Alert
+	seen := make(map[string]bool)
+	ok := false
+	for i, items := range feeds {
+		if errs[i] != nil {
+			if len(p.URLs) > 1 {
+				log.Printf("Failing over from feed: %v", errs[i])
+			}
+			continue
+		}
+		ok = true
+		for _, item := range items {
+			// Mirrors repeat the same items; keep the first copy.
+			key := item.Title + "\x00" + item.Link
+			if item.PublishedParsed != nil {
+				key += "\x00" + item.PublishedParsed.String()
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			alerts = append(alerts, p.itemAlerts(item)...)
+		}
+	}
+	if !ok {
+		return nil, errors.Join(errs...)
+	}
+	return alerts, nil
+}
+
+// fetch downloads and parses a feed. If the feed is not valid XML, its items
+// are extracted leniently instead of failing outright.
+func (p *FeedProvider) fetch(ctx context.Context, url string) ([]*gofeed.Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := readLimited(resp.Body, maxFeedSize)
+	if err != nil {
+		return nil, err
+	}
+	// gofeed.Parser isn't safe for concurrent use, and the feeds are
+	// fetched concurrently, so use one per fetch.
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err == nil {
+		return feed.Items, nil
+	}
+	if items := lenientItems(body); len(items) > 0 {
+		log.Printf("Feed failed to parse (%v); recovered %d items leniently", err, len(items))
+		return items, nil
+	}
+	return nil, err
+}
+
+// readLimited reads all of r, failing if it's longer than limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("response is larger than %d bytes", limit)
+	}
+	return b, nil
+}
+
+// itemAlerts converts a feed item into one alert per clause of its title.
+func (p *FeedProvider) itemAlerts(i *gofeed.Item) []*Alert {
+	desc := i.Description
+	if desc == "" {
+		desc = i.Content
+	}
+	desc = plainText(desc)
+
+	var alerts []*Alert
+	clauses := splitClauses(i.Title)
+	status := p.status(i.Title)
+	for _, c := range clauses {
+		// A clause without a keyword of its own continues the previous
+		// one, as in "Closed: NE 124th St; Tolt Hill Rd".
+		if s, ok := p.grade(c); ok {
+			status = s
+		}
+		a := &Alert{
+			Status:      status,
+			Reason:      classifyReason(c+"\n"+desc, status),
+			Title:       i.Title,
+			Description: desc,
+			Link:        i.Link,
+			Published:   i.PublishedParsed,
+		}
+		if len(clauses) > 1 {
+			a.Scope = c
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
+
+// splitClauses splits an alert title into the clauses of a multi-road
+// alert.
+func splitClauses(title string) []string {
+	var clauses []string
+	for _, c := range strings.FieldsFunc(title, func(r rune) bool {
+		return r == ';' || r == '|' || r == '\n'
+	}) {
+		if c = strings.TrimSpace(c); c != "" {
+			clauses = append(clauses, c)
+		}
+	}
+	if len(clauses) == 0 {
+		return []string{title}
+	}
+	return clauses
+}
+
+// status grades an alert title, defaulting to open.
+func (p *FeedProvider) status(title string) Status {
+	if s, ok := p.grade(title); ok {
+		return s
+	}
+	return StatusOpen
+}
+
+// grade returns the status of the first keyword in text, if any.
+func (p *FeedProvider) grade(text string) (Status, bool) {
+	lower := strings.ToLower(text)
+	for _, k := range p.Keywords {
+		if strings.Contains(lower, strings.ToLower(k.Phrase)) {
+			return k.Status, true
+		}
+	}
+	return StatusUnknown, false
+}
+
+// Source implements Provider.
+func (p *FeedProvider) Source() (name, link string) {
+	return p.Name, p.Link
+}
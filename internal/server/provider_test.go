@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/feeds"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestFeedProviderStatus(t *testing.T) {
+	p := KingCounty()
+	tests := []struct {
+		title string
+		want  Status
+	}{
+		{"Closed - 124th", StatusClosed},
+		{"Open - 124th", StatusOpen},
+		{"Water over roadway - 124th", StatusAdvisory},
+		{"HIGH WATER - 124th", StatusAdvisory},
+		{"Closed to through traffic, local access only - 124th", StatusPartial},
+		{"One lane open with flaggers - 124th", StatusPartial},
+		{"CLOSED - 124th", StatusClosed},
+		{"Road Closed - 124th", StatusClosed},
+		{"Reopened - 124th (was closed for flooding)", StatusOpen},
+		{"124th - Open to traffic", StatusOpen},
+	}
+	for _, tc := range tests {
+		if got := p.status(tc.title); got != tc.want {
+			t.Errorf("status(%q) = %s, want %s", tc.title, got, tc.want)
+		}
+	}
+}
+
+func TestItemAlerts(t *testing.T) {
+	p := KingCounty()
+	alerts := p.itemAlerts(&gofeed.Item{
+		Title: "Closed: Tolt Hill Rd; Reopened: NE 124th St; NE Carnation Farm Rd",
+	})
+	want := []struct {
+		scope  string
+		status Status
+	}{
+		{"Closed: Tolt Hill Rd", StatusClosed},
+		{"Reopened: NE 124th St", StatusOpen},
+		{"NE Carnation Farm Rd", StatusOpen},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("Expected %d alerts, got %d", len(want), len(alerts))
+	}
+	for i, w := range want {
+		if a := alerts[i]; a.Scope != w.scope || a.Status != w.status {
+			t.Errorf("Alert %d: expected %q %s, got %q %s", i, w.scope, w.status, a.Scope, a.Status)
+		}
+	}
+
+	alerts = p.itemAlerts(&gofeed.Item{Title: "Closed: NE 124th St, Tolt Hill Rd"})
+	if len(alerts) != 1 || alerts[0].Scope != "" || alerts[0].Status != StatusClosed {
+		t.Errorf("Expected a single closed alert, got %+v", alerts)
+	}
+}
+
+func TestFeedProviderFailover(t *testing.T) {
+	link := &feeds.Link{Href: "http://localhost"}
+	primary := startTestServer(t, newFeedGenerator(t, []*feeds.Item{
+		{Title: "Closed - 124th", Link: link},
+	}))
+	mirror := startTestServer(t, newFeedGenerator(t, []*feeds.Item{
+		{Title: "Closed - 124th", Link: link},
+		{Title: "Closed - Tolt Hill Rd", Link: link},
+	}))
+	down := startTestServer(t, http.NotFoundHandler())
+
+	p := NewFeedProvider(DefaultKeywords, down, primary, mirror)
+	alerts, err := p.Alerts(context.Background())
+	if err != nil {
+		t.Fatalf("Alerts failed: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Errorf("Expected 2 merged alerts, got %d", len(alerts))
+	}
+
+	p = NewFeedProvider(DefaultKeywords, down, down)
+	if _, err := p.Alerts(context.Background()); err == nil {
+		t.Error("Expected an error when every feed is down")
+	}
+}
+
+// TestFeedProviderConcurrent fetches several feeds from several goroutines
+// at once, for the race detector.
+func TestFeedProviderConcurrent(t *testing.T) {
+	link := &feeds.Link{Href: "http://localhost"}
+	var urls []string
+	for i := 0; i < 4; i++ {
+		urls = append(urls, startTestServer(t, newFeedGenerator(t, []*feeds.Item{
+			{Title: "Closed - 124th", Link: link},
+		})))
+	}
+	p := NewFeedProvider(DefaultKeywords, urls...)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Alerts(context.Background()); err != nil {
+				t.Errorf("Alerts failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFeedProviderUserAgent(t *testing.T) {
+	got := make(chan string, 1)
+	fg := newFeedGenerator(t, nil)
+	feed := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.UserAgent()
+		fg.ServeHTTP(w, r)
+	}))
+
+	p := NewFeedProvider(DefaultKeywords, feed)
+	p.UserAgent = "flood (+mailto:ops@example.com)"
+	if _, err := p.Alerts(context.Background()); err != nil {
+		t.Fatalf("Alerts failed: %v", err)
+	}
+	if ua := <-got; ua != p.UserAgent {
+		t.Errorf("Expected User-Agent %q, got %q", p.UserAgent, ua)
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if b, err := readLimited(strings.NewReader("12345"), 5); err != nil || string(b) != "12345" {
+		t.Errorf("Expected the whole body, got %q, %v", b, err)
+	}
+	if _, err := readLimited(strings.NewReader("123456"), 5); err == nil {
+		t.Error("Expected an error for an oversized body")
+	}
+}
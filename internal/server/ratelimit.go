@@ -0,0 +1,126 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket rate limiter per client.
+type limiter struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newLimiter returns a limiter that allows each client rate requests per
+// second on average, and up to burst at once. A zero rate disables it.
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{now: time.Now, rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from client's bucket. If there are none, it returns
+// false and how long until there will be.
+func (l *limiter) allow(client string) (bool, time.Duration) {
+	if l.rate == 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets clients whose buckets have refilled, at most once a minute,
+// so that memory is bounded by the clients active in that time.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for c, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, c)
+		}
+	}
+}
+
+// clientIP returns the client's IP address. X-Forwarded-For is only
+// believed as far back as the chain of trusted proxies goes, since anyone
+// can send the header.
+func (h *handler) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !h.trusted(host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		host = hop
+		if !h.trusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trusted reports whether addr is one of the trusted proxies.
+func (h *handler) trusted(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	for _, p := range h.proxies {
+		if p.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimited responds with 429 Too Many Requests, and reports true, if the
+// client has made too many requests.
+func (h *handler) rateLimited(w http.ResponseWriter, r *http.Request) bool {
+	ok, wait := h.limiter.allow(h.clientIP(r))
+	if ok {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+	return true
+}
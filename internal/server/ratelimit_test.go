@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2021, 11, 15, 3, 41, 0, 0, time.UTC)
+	l := newLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("Request %d: expected the burst to be allowed", i)
+		}
+	}
+	ok, wait := l.allow("a")
+	if ok || wait != time.Second {
+		t.Errorf("Expected to wait 1s after the burst, got %t %v", ok, wait)
+	}
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("Expected other clients to be unaffected")
+	}
+	now = now.Add(time.Second)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("Expected a token after a second")
+	}
+
+	now = now.Add(time.Hour)
+	l.allow("c")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("Expected idle clients to be forgotten")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider:       &fakeProvider{},
+		Road:           "124th",
+		RateLimit:      0.001,
+		RateBurst:      1,
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func(remote, xff string) int {
+		r := httptest.NewRequest(http.MethodGet, "/status.txt", nil)
+		r.RemoteAddr = remote
+		if xff != "" {
+			r.Header.Set("X-Forwarded-For", xff)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if code := get("192.0.2.1:1234", ""); code != http.StatusOK {
+		t.Fatalf("Expected the first request to be allowed, got %d", code)
+	}
+	if code := get("192.0.2.1:1234", ""); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %d", code)
+	}
+	// An untrusted peer can't dodge the limit with X-Forwarded-For.
+	if code := get("192.0.2.1:1234", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected spoofed X-Forwarded-For to be ignored, got %d", code)
+	}
+	// Behind a trusted proxy, each forwarded client has its own limit.
+	if code := get("10.0.0.2:1234", "192.0.2.1, 198.51.100.8"); code != http.StatusOK {
+		t.Errorf("Expected a new client behind the proxy to be allowed, got %d", code)
+	}
+	if code := get("10.0.0.2:1234", "198.51.100.8"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the same client behind the proxy to be limited, got %d", code)
+	}
+	// Health checks aren't limited.
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected /readyz to be exempt, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// warmTimeout bounds each startup poll.
+const warmTimeout = 30 * time.Second
+
+// warm polls the provider once at startup, retrying with backoff until it
+// succeeds, and then marks the handler ready. This seeds the status tracker
+// so that changes are observed from boot rather than from the first visit,
+// and lets /readyz hold traffic until the upstream has answered.
+func (h *handler) warm() {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	backoff := time.Second
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+		_, err := h.current(r.WithContext(ctx))
+		cancel()
+		if err == nil {
+			h.ready.Store(true)
+			return
+		}
+		h.warnf(r, "startup poll failed, retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// poll refreshes the status every interval, forever. While the road is
+// affected (anything but open), or its status is unknown, it polls every
+// active interval instead, if that is set.
+func (h *handler) poll(interval, active time.Duration) {
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	next := interval
+	for {
+		time.Sleep(next)
+		ctx, cancel := context.WithTimeout(context.Background(), warmTimeout)
+		td, err := h.current(r.WithContext(ctx))
+		cancel()
+		if err != nil {
+			h.warnf(r, "background poll failed: %v", err)
+		}
+		next = interval
+		if active > 0 && (err != nil || td.Status != StatusOpen) {
+			next = active
+		}
+	}
+}
+
+// readyz reports whether the startup poll has completed.
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		http.Error(w, "warming up", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reason is why a road is closed or restricted.
+type Reason string
+
+const (
+	ReasonNone         Reason = ""
+	ReasonFlooding     Reason = "flooding"
+	ReasonCollision    Reason = "collision"
+	ReasonConstruction Reason = "construction"
+	ReasonTrees        Reason = "downed trees"
+	ReasonOther        Reason = "other"
+)
+
+// reasonKeywords maps lowercase alert text to a reason. Keywords match at
+// the start of a word, so "collision" matches "collisions". Flooding and
+// trees are matched by phrase, since road names like "Flood Rd", "Waterfront
+// Dr" and "Treemont" start with those words too. The first matching entry
+// wins, so more specific phrases come first.
+var reasonKeywords = []struct {
+	keyword string
+	reason  Reason
+}{
+	{"flooding", ReasonFlooding},
+	{"flooded", ReasonFlooding},
+	{"floodwater", ReasonFlooding},
+	{"flood water", ReasonFlooding},
+	{"high water", ReasonFlooding},
+	{"standing water", ReasonFlooding},
+	{"water over", ReasonFlooding},
+	{"water on", ReasonFlooding},
+	{"water across", ReasonFlooding},
+	{"collision", ReasonCollision},
+	{"crash", ReasonCollision},
+	{"accident", ReasonCollision},
+	{"construction", ReasonConstruction},
+	{"roadwork", ReasonConstruction},
+	{"road work", ReasonConstruction},
+	{"paving", ReasonConstruction},
+	{"tree down", ReasonTrees},
+	{"trees down", ReasonTrees},
+	{"fallen tree", ReasonTrees},
+	{"downed tree", ReasonTrees},
+	{"tree across", ReasonTrees},
+	{"tree in road", ReasonTrees},
+	{"tree on road", ReasonTrees},
+	{"power line", ReasonTrees},
+}
+
+// classifyReason returns why an alert with the given text and status
+// affects the road. Open roads have no reason.
+func classifyReason(text string, status Status) Reason {
+	if status == StatusOpen || status == StatusUnknown {
+		return ReasonNone
+	}
+	lower := strings.ToLower(text)
+	for _, k := range reasonKeywords {
+		if containsWord(lower, k.keyword) {
+			return k.reason
+		}
+	}
+	return ReasonOther
+}
+
+// containsWord reports whether s contains keyword at the start of a word,
+// so that, for example, "tree" doesn't match "street".
+func containsWord(s, keyword string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], keyword)
+		if j < 0 {
+			return false
+		}
+		j += i
+		if r, _ := utf8.DecodeLastRuneInString(s[:j]); j == 0 || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return true
+		}
+		i = j + 1
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import "testing"
+
+func TestClassifyReason(t *testing.T) {
+	tests := []struct {
+		text   string
+		status Status
+		want   Reason
+	}{
+		{"Closed - 124th - Flooding", StatusClosed, ReasonFlooding},
+		{"Water over roadway - 124th", StatusAdvisory, ReasonFlooding},
+		{"Closed - 124th - Collision", StatusClosed, ReasonCollision},
+		{"One lane open with flaggers - 124th - Construction", StatusPartial, ReasonConstruction},
+		{"Closed - 124th - Tree down", StatusClosed, ReasonTrees},
+		{"Closed - 124th", StatusClosed, ReasonOther},
+		{"Closed - NE 124th Street", StatusClosed, ReasonOther},
+		{"Closed - Treemont Rd - Fallen trees", StatusClosed, ReasonTrees},
+		{"Closed - 124th - Downed power lines", StatusClosed, ReasonTrees},
+		{"Open - 124th - Flooding cleared", StatusOpen, ReasonNone},
+		{"Closed - Waterfront Dr - Collision", StatusClosed, ReasonCollision},
+		{"Closed - NE Flood Rd - Crash", StatusClosed, ReasonCollision},
+		{"Closed - NE Flood Rd - High water", StatusClosed, ReasonFlooding},
+		{"Closed - Waterfront Dr", StatusClosed, ReasonOther},
+	}
+	for _, tc := range tests {
+		if got := classifyReason(tc.text, tc.status); got != tc.want {
+			t.Errorf("classifyReason(%q, %s) = %q, want %q", tc.text, tc.status, got, tc.want)
+		}
+	}
+}
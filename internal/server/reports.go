@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// reportWindow is how long crowd reports are shown.
+	reportWindow = 2 * time.Hour
+	// reportInterval is how often a single client may report.
+	reportInterval = 10 * time.Minute
+	// maxReports bounds the memory used by reports.
+	maxReports = 200
+)
+
+// crowdReport is a visitor's unverified "I just drove it" report.
+type crowdReport struct {
+	open bool
+	at   time.Time
+}
+
+// reportSummary counts recent crowd reports for display. Reports are a
+// low-trust hint shown alongside the status; they never change it.
+type reportSummary struct {
+	Open   int `json:"open"`
+	Closed int `json:"closed"`
+}
+
+// reports holds recent crowd reports in memory, rate limited per client.
+type reports struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	list    []crowdReport
+	clients map[string]time.Time
+}
+
+func newReports() *reports {
+	return &reports{now: time.Now, clients: make(map[string]time.Time)}
+}
+
+// add records a report from client and reports whether it was accepted.
+func (rs *reports) add(client string, open bool) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	now := rs.now()
+	rs.expire(now)
+	if last, ok := rs.clients[client]; ok && now.Sub(last) < reportInterval {
+		return false
+	}
+	rs.clients[client] = now
+	rs.list = append(rs.list, crowdReport{open: open, at: now})
+	if len(rs.list) > maxReports {
+		rs.list = rs.list[len(rs.list)-maxReports:]
+	}
+	return true
+}
+
+// summary counts the reports in the window, or returns nil if there are
+// none.
+func (rs *reports) summary() *reportSummary {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.expire(rs.now())
+	if len(rs.list) == 0 {
+		return nil
+	}
+	s := &reportSummary{}
+	for _, r := range rs.list {
+		if r.open {
+			s.Open++
+		} else {
+			s.Closed++
+		}
+	}
+	return s
+}
+
+// expire drops reports and rate limits older than their windows.
+func (rs *reports) expire(now time.Time) {
+	i := 0
+	for i < len(rs.list) && now.Sub(rs.list[i].at) > reportWindow {
+		i++
+	}
+	rs.list = rs.list[i:]
+	for c, t := range rs.clients {
+		if now.Sub(t) >= reportInterval {
+			delete(rs.clients, c)
+		}
+	}
+}
+
+// report accepts a crowd report from the page's form. The "website" field
+// is a honeypot hidden from people; bots that fill it in are ignored.
+func (h *handler) report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.reportsMuted.Load() {
+		http.Error(w, "reports are paused", http.StatusServiceUnavailable)
+		return
+	}
+	var open bool
+	switch r.PostFormValue("status") {
+	case "open":
+		open = true
+	case "closed":
+	default:
+		http.Error(w, "status must be open or closed", http.StatusBadRequest)
+		return
+	}
+	if r.PostFormValue("website") == "" && !h.reports.add(h.clientIP(r), open) {
+		http.Error(w, "you've already reported recently; thanks!", http.StatusTooManyRequests)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
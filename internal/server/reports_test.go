@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReports(t *testing.T) {
+	now := time.Date(2021, 11, 15, 3, 0, 0, 0, time.UTC)
+	rs := newReports()
+	rs.now = func() time.Time { return now }
+
+	if rs.summary() != nil {
+		t.Error("Expected no summary without reports")
+	}
+	if !rs.add("a", true) || !rs.add("b", false) {
+		t.Fatal("Expected first reports to be accepted")
+	}
+	if rs.add("a", false) {
+		t.Error("Expected a repeat report to be rate limited")
+	}
+	if s := rs.summary(); s.Open != 1 || s.Closed != 1 {
+		t.Errorf("Expected 1 open and 1 closed, got %+v", s)
+	}
+	now = now.Add(reportInterval)
+	if !rs.add("a", false) {
+		t.Error("Expected a report after the interval to be accepted")
+	}
+	now = now.Add(reportWindow)
+	if s := rs.summary(); s.Closed != 1 || s.Open != 0 {
+		t.Errorf("Expected old reports to expire, got %+v", s)
+	}
+}
+
+func TestReportForm(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider:     &fakeProvider{},
+		Road:         "124th",
+		CrowdReports: true,
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	post := func(form url.Values) int {
+		r := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	if code := post(url.Values{"status": {"closed"}, "website": {"spam"}}); code != http.StatusSeeOther {
+		t.Errorf("Expected honeypot submission to look accepted, got %d", code)
+	}
+	if code := post(url.Values{"status": {"closed"}}); code != http.StatusSeeOther {
+		t.Errorf("Expected 303, got %d", code)
+	}
+	if code := post(url.Values{"status": {"closed"}}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "0 open, 1 closed") {
+		t.Errorf("Expected report summary in body: %s", body)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the status data contract: the fields
+// available to templates and returned by the API. Adding a field is
+// backwards compatible; renaming or removing one requires a new version.
+const SchemaVersion = 1
+
+// schema serves the JSON Schema of templateData.
+func schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(statusSchema())
+}
+
+// statusSchema returns the versioned JSON Schema of templateData.
+func statusSchema() map[string]interface{} {
+	s := jsonSchema(reflect.TypeOf(templateData{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "Road status"
+	s["version"] = SchemaVersion
+	return s
+}
+
+var (
+	statusType = reflect.TypeOf(Status(0))
+	reasonType = reflect.TypeOf(Reason(""))
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// jsonSchema describes how encoding/json marshals values of type t. It
+// supports only the kinds used by the status data.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case statusType:
+		var enum []string
+		for _, s := range statuses {
+			enum = append(enum, s.String())
+		}
+		return map[string]interface{}{"type": "string", "enum": enum}
+	case reasonType:
+		return map[string]interface{}{"type": "string", "enum": []Reason{
+			ReasonFlooding, ReasonCollision, ReasonConstruction, ReasonTrees, ReasonOther,
+		}}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" || !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = jsonSchema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if required != nil {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -4,18 +4,24 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/netip"
+	"os"
 	"strings"
+	"sync/atomic"
 	"time"
-
-	"github.com/mmcdole/gofeed"
 )
 
+// Override is a manual status that takes the place of the provider's.
 type Override int
 
 const (
@@ -25,41 +31,223 @@ const (
 )
 
 // The data directory contains templates and the favicon.
+//
 //go:embed data
 var data embed.FS
 
+// Segment is a stretch of a road with its own status. An alert applies to
+// the segment if its title contains Match.
+type Segment struct {
+	Name  string `json:"name"`
+	Match string `json:"match"`
+	// DefaultUnknown makes the segment's status Unknown, rather than Open,
+	// while no alert mentions it. Use it for roads that are often closed
+	// without the feed saying so.
+	DefaultUnknown bool `json:"defaultUnknown,omitempty"`
+}
+
+// segmentData is the status of a single segment.
+type segmentData struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Reason Reason `json:"reason,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Description is the plain text of the alert's description, such as
+	// the expected duration or detour.
+	Description string `json:"description,omitempty"`
+	Link        string `json:"link,omitempty"`
+	Published   string `json:"published,omitempty"`
+
+	published time.Time
+}
+
 // templateData contains the fields needed to populate the flood.html
-// template.
+// template. The top-level status is that of the most severely affected
+// segment; Segments is only populated if the road has more than one.
+//
+// templateData is also the contract published at /api/v1/schema. Bump
+// SchemaVersion when fields are renamed or removed.
 type templateData struct {
-	Road      string
-	Open      bool
-	Detail    string
-	Link      string
-	Published string
+	Road        string         `json:"road"`
+	Status      Status         `json:"status"`
+	Reason      Reason         `json:"reason,omitempty"`
+	Detail      string         `json:"detail,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Link        string         `json:"link,omitempty"`
+	Published   string         `json:"published,omitempty"`
+	Segments    []*segmentData `json:"segments,omitempty"`
+	// Changed is when this server last saw the status change, if it has.
+	Changed string `json:"changed,omitempty"`
+	// Source and SourceLink attribute the data to the alert provider.
+	Source     string `json:"source,omitempty"`
+	SourceLink string `json:"sourceLink,omitempty"`
+	// Shareable is set if the page can be frozen into a share snapshot.
+	Shareable bool `json:"shareable,omitempty"`
+	// CrowdReports enables the report form; Reports summarizes recent
+	// unverified reports from visitors, if any.
+	CrowdReports bool           `json:"crowdReports,omitempty"`
+	Reports      *reportSummary `json:"reports,omitempty"`
+	// Snapshot is set, to the time it was taken, if this is a shared
+	// snapshot rather than the live status.
+	Snapshot string `json:"snapshot,omitempty"`
+	// Outage is set, describing the problem, if the provider is unreachable
+	// and this is the last known status.
+	Outage string `json:"outage,omitempty"`
+	// Cameras are shown on the page, but aren't part of the status.
+	Cameras []*cameraGroup `json:"-"`
+	// Unknown explains why the status is StatusUnknown.
+	Unknown string `json:"unknown,omitempty"`
+
+	// since is when the status began, as far as we know: when this server
+	// saw it change, or else when the deciding alert was published.
+	since time.Time
 }
 
 // handler is the HTTP handler for the flood detection service.
 type handler struct {
-	override Override
-	feedURL  string
+	override atomic.Int32
+	provider Provider
 	road     string
+	segments []Segment
+	bundle   *Bundle
 	loc      *time.Location
 	templ    *template.Template
-	parser   *gofeed.Parser
+	bundleT  *template.Template
+	tracker  *tracker
+	notifier *notifier
+	host     string
+	shareKey []byte
+	reports  *reports
+	ready    atomic.Bool
+	watchdog *watchdog
+	outage   *outage
+	versions *versions
+	started  time.Time
+	origins  []string
+	cameras  *cameras
+	token    string
+	adminKey []byte
+	limiter  *limiter
+	proxies  []netip.Prefix
+	timeout  time.Duration
+	maxAge   time.Duration
+	hub      *hub
+	webhooks *deliveries
 	*http.ServeMux
+
+	// feedMuted and reportsMuted are set through /admin/sources.
+	feedMuted    atomic.Bool
+	reportsMuted atomic.Bool
+
+	logFormat  LogFormat
+	gcpProject string
+	logOut     io.Writer
 }
 
-// Options specifies the feed URL for road alerts, the road to check for closures
+// Options specifies the source of road alerts, the road to check for closures
 // in those alerts, and the timezone in which to display time to the user.
-// FeedURL and Road are required. Timezone defaults to UTC.
+// Road and one of Provider or FeedURL are required. Timezone defaults to UTC.
 type Options struct {
 	// If override isn't None, use the manual open/closed status
 	// instead of the feed data (useful for when the feed isn't updated but
 	// the cameras clearly show that the road is open.)
 	Override Override
+	// Provider is the source of road alerts. If nil, FeedURL is read using
+	// DefaultKeywords.
+	Provider Provider
 	FeedURL  string
 	Road     string
+	// Segments split the road into independently reported stretches. If
+	// empty, the whole road is a single segment matched by Road.
+	Segments []Segment
+	// DefaultUnknown sets Segment.DefaultUnknown for Road if Segments
+	// isn't set.
+	DefaultUnknown bool
+	// Bundle optionally serves a summary page for a group of nearby roads.
+	Bundle   *Bundle
 	Timezone string
+	// SilenceThreshold is how long the feed may go unchanged while the
+	// road is affected before a warning is logged for the operator. Zero
+	// disables the check.
+	SilenceThreshold time.Duration
+	// OutageThreshold is how many consecutive provider failures to allow
+	// before serving the last known status with an outage banner instead of
+	// an error; 0 disables it.
+	OutageThreshold int
+	// FetchTimeout, if non-zero, bounds how long a request waits on the
+	// provider. A slow feed then counts as a failure, falling back to the
+	// last known or Unknown status, instead of hanging the page.
+	FetchTimeout time.Duration
+	// OnChange, if set, is called whenever the server observes the road's
+	// status change. Calls are made from a background goroutine, one change
+	// at a time, in order.
+	OnChange func(Change)
+	// Webhooks are URLs to POST each Change to, as JSON. Like OnChange,
+	// delivery happens in the background. Recent deliveries are listed at
+	// GET /admin/webhooks if the admin API is enabled.
+	Webhooks []string
+	// CoalesceWindow, if non-zero, delays OnChange calls by this long and
+	// merges changes within the window into one.
+	CoalesceWindow time.Duration
+	// Cameras are shown on the page, proxied through /cameras/{n}.jpg.
+	Cameras []Camera
+	// Outbound, if set, restricts the camera URLs that may be fetched.
+	// Otherwise any http or https URL may be.
+	Outbound *OutboundPolicy
+	// UserAgent is sent when fetching camera images. It defaults to
+	// DefaultUserAgent.
+	UserAgent string
+	// AllowedOrigins are the origins, such as "https://example.com", whose
+	// scripts may read the API and feeds. "*" allows any origin.
+	AllowedOrigins []string
+	// AdminToken and AdminKey enable the admin API, such as
+	// POST /admin/override. Requests must either carry AdminToken as a
+	// bearer token or be signed with AdminKey (see signAdminRequest).
+	AdminToken string
+	AdminKey   []byte
+	// RateLimit, if non-zero, is how many requests per second each client
+	// may make on average, with bursts of up to RateBurst. Clients over
+	// the limit get 429 Too Many Requests.
+	RateLimit float64
+	RateBurst int
+	// MaxAge, if non-zero, lets browsers and CDNs cache the status page,
+	// /api/v1/status, and /status.txt for this long, so that a spike in
+	// visitors doesn't reach the provider. Changes can then take up to
+	// MaxAge to show.
+	MaxAge time.Duration
+	// MaxStreams limits concurrent /events and /ws connections; more are
+	// refused with 503. It defaults to DefaultMaxStreams.
+	MaxStreams int
+	// TrustedProxies are the networks of proxies, such as a load balancer,
+	// whose X-Forwarded-For headers identify clients for rate limiting.
+	TrustedProxies []netip.Prefix
+	// CanonicalHost, if set, is the only host name the site answers to.
+	// Requests for any other host, such as an alias or the raw IP, are
+	// permanently redirected to it.
+	CanonicalHost string
+	// ShareKey, if set, enables share snapshots: signed, immutable URLs
+	// that show the status as it was when they were created.
+	ShareKey []byte
+	// CrowdReports lets visitors report whether they just drove the road.
+	// Reports are rate limited per client and shown as an unverified hint
+	// next to the status; they never change it.
+	CrowdReports bool
+	// WarmUp polls the provider in the background at startup, and
+	// /readyz fails until that poll succeeds. Otherwise /readyz is
+	// always ready.
+	WarmUp bool
+	// PollInterval, if non-zero, polls the provider in the background this
+	// often, so that changes are noticed and pushed to /events subscribers
+	// even when nobody is loading the page.
+	PollInterval time.Duration
+	// ActivePollInterval, if non-zero, replaces PollInterval while the road
+	// isn't open, so that conditions are tracked closely during a flood.
+	ActivePollInterval time.Duration
+	// LogFormat selects plain text (the default) or Cloud Logging JSON.
+	LogFormat LogFormat
+	// GCPProject is the project ID used to link GCPLog entries to Cloud
+	// Trace. Trace fields are omitted if it is empty.
+	GCPProject string
 }
 
 // NewHandler returns an http.Handler for
@@ -79,78 +267,467 @@ func NewHandler(opts *Options) (http.Handler, error) {
 		return nil, err
 	}
 
-	s := &handler{opts.Override, opts.FeedURL, opts.Road, loc, t, gofeed.NewParser(), http.NewServeMux()}
+	p := opts.Provider
+	if p == nil {
+		p = NewFeedProvider(DefaultKeywords, opts.FeedURL)
+	}
+
+	cameras, err := newCameras(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := opts.Segments
+	if len(segments) == 0 {
+		segments = []Segment{{Name: opts.Road, Match: opts.Road, DefaultUnknown: opts.DefaultUnknown}}
+	}
+
+	if opts.Override != None {
+		log.Printf("Manual override! closed=%t", opts.Override == Closed)
+	}
+
+	s := &handler{
+		provider:   p,
+		road:       opts.Road,
+		segments:   segments,
+		bundle:     opts.Bundle,
+		tracker:    newTracker(),
+		host:       opts.CanonicalHost,
+		shareKey:   opts.ShareKey,
+		watchdog:   newWatchdog(opts.SilenceThreshold),
+		outage:     newOutage(opts.OutageThreshold),
+		versions:   newVersions(),
+		started:    time.Now(),
+		origins:    opts.AllowedOrigins,
+		cameras:    cameras,
+		token:      opts.AdminToken,
+		adminKey:   opts.AdminKey,
+		limiter:    newLimiter(opts.RateLimit, opts.RateBurst),
+		proxies:    opts.TrustedProxies,
+		timeout:    opts.FetchTimeout,
+		maxAge:     opts.MaxAge,
+		hub:        newHub(opts.MaxStreams),
+		webhooks:   &deliveries{},
+		loc:        loc,
+		templ:      t,
+		ServeMux:   http.NewServeMux(),
+		logFormat:  opts.LogFormat,
+		gcpProject: opts.GCPProject,
+		logOut:     os.Stderr,
+	}
+	hooks := make([]func(Change), 0, len(opts.Webhooks)+1)
+	if opts.OnChange != nil {
+		hooks = append(hooks, opts.OnChange)
+	}
+	for _, url := range opts.Webhooks {
+		hooks = append(hooks, webhook(url, s.webhooks))
+	}
+	if len(hooks) > 0 {
+		s.notifier = &notifier{window: opts.CoalesceWindow, hook: func(c Change) {
+			for _, hook := range hooks {
+				hook(c)
+			}
+		}}
+	}
 	s.Handle("/favicon.ico", http.FileServer(http.FS(fs)))
-	s.HandleFunc("/", logged(s.flood()))
+	s.HandleFunc("/", s.logged(s.flood()))
+	s.HandleFunc("/api/openapi.json", s.logged(s.cors(openAPIDoc)))
+	s.HandleFunc("/api/v1/schema", s.logged(s.cors(schema)))
+	s.HandleFunc("/api/v1/status", s.logged(s.cors(s.apiStatus)))
+	s.HandleFunc("/api/v1/device", s.logged(s.cors(s.device)))
+	s.HandleFunc("/status.txt", s.logged(s.cors(s.statusText)))
+	s.HandleFunc("/stats", s.logged(s.cors(s.stats)))
+	s.HandleFunc("/api/v1/metrics", s.logged(s.cors(s.metrics)))
+	s.HandleFunc("/api/v1/history", s.logged(s.cors(s.history)))
+	s.HandleFunc("/api/v1/feed", s.logged(s.cors(s.feedItems)))
+	s.HandleFunc("/feed.xml", s.logged(s.cors(s.feed)))
+	s.HandleFunc("/closures.ics", s.logged(s.cors(s.calendar)))
+	s.HandleFunc("/events", s.logged(s.cors(s.events)))
+	s.HandleFunc("/ws", s.logged(s.ws))
+	s.HandleFunc("/readyz", s.readyz)
+	s.HandleFunc("GET /cameras/{file}", s.logged(s.camera))
+	if opts.CrowdReports {
+		s.reports = newReports()
+		s.HandleFunc("/report", s.logged(s.report))
+	}
+	if len(s.shareKey) > 0 {
+		s.HandleFunc("/share", s.logged(s.share))
+		s.HandleFunc("/s/", s.logged(s.snapshotPage))
+	}
+	if s.bundle != nil {
+		if s.bundleT, err = template.ParseFS(data, "data/bundle.html"); err != nil {
+			return nil, err
+		}
+		s.HandleFunc(s.bundle.Path, s.logged(s.bundlePage()))
+	}
+
+	s.override.Store(int32(opts.Override))
+	if opts.AdminToken != "" || len(opts.AdminKey) > 0 {
+		s.HandleFunc("POST /admin/override", s.logged(s.admin(s.setOverride)))
+		s.HandleFunc("/admin/sources", s.logged(s.admin(s.setSources)))
+		s.HandleFunc("GET /admin/webhooks", s.logged(s.admin(s.webhookLog)))
+	}
+
+	if opts.WarmUp {
+		go s.warm()
+	} else {
+		s.ready.Store(true)
+	}
+	if opts.PollInterval > 0 {
+		go s.poll(opts.PollInterval, opts.ActivePollInterval)
+	}
 
 	return s, nil
 }
 
+// ServeHTTP redirects requests for non-canonical hosts, rate limits
+// clients, and otherwise dispatches to the mux.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.host != "" && !strings.EqualFold(r.Host, h.host) {
+		u := *r.URL
+		u.Scheme = scheme(r)
+		u.Host = h.host
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return
+	}
+	// Health checks come from the same few addresses, often.
+	if r.URL.Path != "/readyz" && h.rateLimited(w, r) {
+		return
+	}
+	gw := &gzipWriter{ResponseWriter: w, accepts: acceptsGzip(r)}
+	defer gw.close()
+	h.ServeMux.ServeHTTP(gw, r)
+}
+
+// scheme returns the scheme the client used, respecting X-Forwarded-Proto to
+// support running behind a proxy.
+func scheme(r *http.Request) string {
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
+
 // flood pulls the latest road alerts, gets the latest for the given road,
 // and populates the template based on the results.
 func (h *handler) flood() http.HandlerFunc {
-	// If the manual override is set, execute the template once
-	if h.override != None {
-		buffy := &bytes.Buffer{}
-		td := &templateData{
-			Open: h.override == Open,
-			Road: h.road,
+	return func(w http.ResponseWriter, r *http.Request) {
+		td, err := h.current(r)
+		failed := err != nil
+		if failed {
+			// Rather than an error page, say that we don't know.
+			td = h.unknown(r, err)
 		}
-		log.Printf("Manual override! open=%t", td.Open)
-		if err := h.templ.Execute(buffy, td); err != nil {
-			log.Fatalf("Failed to execute manual override: %v", err)
+		// Clients that want the status as data can use the same URL.
+		mt := negotiate(r.Header.Get("Accept"), "text/html", "application/json", "text/plain")
+		var b bytes.Buffer
+		switch mt {
+		case "application/json":
+			if err := json.NewEncoder(&b).Encode(td); err != nil {
+				h.internalError(w, r, "internal error: %v", err)
+				return
+			}
+		case "text/plain":
+			fmt.Fprintln(&b, strings.ToUpper(td.Status.String()))
+		default:
+			td.Cameras = h.cameras.groups
+			if err := h.templ.Execute(&b, td); err != nil {
+				h.internalError(w, r, "internal error: %v", err)
+				return
+			}
+			mt += "; charset=utf-8"
 		}
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Write(buffy.Bytes())
+		w.Header().Add("Vary", "Accept")
+		if failed {
+			h.unavailable(w, r, mt, b.Bytes())
+			return
 		}
+		w.Header().Set("X-Road-Status", td.Status.String())
+		w.Header().Set("Content-Type", mt)
+		h.serveConditional(w, r, "/ "+mt, b.Bytes())
 	}
+}
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		feed, err := h.parser.ParseURLWithContext(h.feedURL, r.Context())
-		if err != nil {
-			internalError(w, "failed to fetch the road alert feed: %v", err)
-			return
+// unknown logs err, a failure to determine the road's status, and returns an
+// Unknown status explaining it to show instead.
+func (h *handler) unknown(r *http.Request, err error) *templateData {
+	td := &templateData{Road: h.road, Status: StatusUnknown}
+	td.Source, td.SourceLink = h.provider.Source()
+	switch {
+	case errors.Is(err, errFeedMuted):
+		td.Unknown = mutedUnknown
+	case td.Source != "":
+		td.Unknown = fmt.Sprintf("The %s road alert feed couldn't be reached.", td.Source)
+	default:
+		td.Unknown = "The road alert feed couldn't be reached."
+	}
+	if !errors.Is(err, errFeedMuted) {
+		h.errorf(r, "failed to fetch road alerts: %v", err)
+	}
+	return td
+}
+
+// unavailable serves body, a rendering of an Unknown status, with 503 so
+// that monitors and caches don't take it for the road's real status.
+func (h *handler) unavailable(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	w.Header().Set("X-Road-Status", StatusUnknown.String())
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// unavailableJSON serves the Unknown status for err as JSON, as / does for
+// clients that accept it.
+func (h *handler) unavailableJSON(w http.ResponseWriter, r *http.Request, err error) {
+	b, err := json.Marshal(h.unknown(r, err))
+	if err != nil {
+		h.internalError(w, r, "internal error: %v", err)
+		return
+	}
+	h.unavailable(w, r, "application/json", append(b, '\n'))
+}
+
+// apiStatus serves the road status as JSON.
+func (h *handler) apiStatus(w http.ResponseWriter, r *http.Request) {
+	td, err := h.current(r)
+	if err != nil {
+		h.unavailableJSON(w, r, err)
+		return
+	}
+	b, err := json.Marshal(td)
+	if err != nil {
+		h.internalError(w, r, "internal error: %v", err)
+		return
+	}
+	w.Header().Set("X-Road-Status", td.Status.String())
+	w.Header().Set("Content-Type", "application/json")
+	h.serveConditional(w, r, "/api/v1/status", append(b, '\n'))
+}
+
+// statusText serves the road status as plain text: the status in capitals
+// on the first line (e.g. OPEN or CLOSED), followed by the alert detail on a
+// second line if there is one.
+func (h *handler) statusText(w http.ResponseWriter, r *http.Request) {
+	td, err := h.current(r)
+	if err != nil {
+		// Like / for plain text clients, just say that we don't know.
+		h.unknown(r, err)
+		h.unavailable(w, r, "text/plain; charset=utf-8", []byte(strings.ToUpper(StatusUnknown.String())+"\n"))
+		return
+	}
+	var b bytes.Buffer
+	fmt.Fprintln(&b, strings.ToUpper(td.Status.String()))
+	if td.Detail != "" {
+		fmt.Fprintln(&b, td.Detail)
+	}
+	w.Header().Set("X-Road-Status", td.Status.String())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	h.serveConditional(w, r, "/status.txt", b.Bytes())
+}
+
+// current computes the road's status for a request. If the manual override
+// is set, the feed isn't consulted at all.
+func (h *handler) current(r *http.Request) (*templateData, error) {
+	td, err := h.compute(r)
+	if err != nil {
+		return nil, err
+	}
+	h.decorate(td)
+	return td, nil
+}
+
+// decorate fills in the parts of td that don't come from the status: its
+// source, and whether to show the share button and crowd reports.
+func (h *handler) decorate(td *templateData) {
+	td.Source, td.SourceLink = h.provider.Source()
+	td.Shareable = len(h.shareKey) > 0
+	if h.reports != nil && !h.reportsMuted.Load() {
+		td.CrowdReports = true
+		td.Reports = h.reports.summary()
+	}
+}
+
+// compute determines the road's status from the override or the provider.
+func (h *handler) compute(r *http.Request) (*templateData, error) {
+	started := time.Now()
+	if o := Override(h.override.Load()); o != None {
+		td := &templateData{Status: StatusOpen, Road: h.road}
+		if o == Closed {
+			td.Status = StatusClosed
 		}
+		h.observe(td, "override", started)
+		return td, nil
+	}
+	if h.feedMuted.Load() {
+		// Unknown isn't a change in the road, so it isn't observed.
+		return &templateData{Road: h.road, Status: StatusUnknown, Unknown: mutedUnknown}, nil
+	}
 
-		// The road is assumed to be open by default. It is only considered
-		// closed if the item is mentioned in the feed and the feed item's
-		// title starts with the literal "Closed". This is potentially fragile,
-		// but the KC RSS feed seems to follow this convention.
-		td := &templateData{Open: true, Road: h.road}
-		for _, i := range feed.Items {
-			if strings.Contains(i.Title, h.road) {
-				td.Open = !strings.HasPrefix(i.Title, "Closed")
-				td.Detail = i.Title
-				td.Link = i.Link
-				if i.PublishedParsed != nil {
-					td.Published = i.PublishedParsed.In(h.loc).Format(time.RFC1123)
-				}
-				break
+	alerts, err := h.alerts(r)
+	if err != nil {
+		if td, since, ok := h.outage.fail(); ok {
+			h.warnf(r, "showing last known status: %v", err)
+			name, _ := h.provider.Source()
+			if name == "" {
+				name = "The road alert"
 			}
+			td.Outage = fmt.Sprintf("%s feed unreachable since %s — showing last known status.", name, since.In(h.loc).Format("3:04 PM"))
+			return td, nil
 		}
+		return nil, err
+	}
+	td := h.evaluate(alerts)
+	if silent, ok := h.watchdog.check(alerts, td.Status); ok {
+		h.warnf(r, "road alert feed unchanged for %s while %s is %s; upstream may be stuck", silent.Round(time.Minute), h.road, td.Status)
+	}
+	source, _ := h.provider.Source()
+	h.observe(td, source, started)
+	h.outage.ok(td, len(alerts))
+	return td, nil
+}
 
-		if err := h.templ.Execute(w, td); err != nil {
-			internalError(w, "internal error: %v", err)
+// observe records the status in td, fetched at started, with the tracker,
+// noting when it changed. If it did, hooks are notified and the status is
+// published to the live clients, in the order the tracker recorded it.
+func (h *handler) observe(td *templateData, source string, started time.Time) {
+	changed, flip, ok := h.tracker.observe(Change{
+		Road:   h.road,
+		To:     td.Status,
+		Reason: td.Reason,
+		Detail: td.Detail,
+		Link:   td.Link,
+		Source: source,
+	}, started)
+	if !changed.IsZero() {
+		td.Changed = changed.In(h.loc).Format("3:04 PM on Mon, Jan 2")
+		td.since = changed
+	}
+	if !ok {
+		return
+	}
+	if flip != nil && h.notifier != nil {
+		h.notifier.notify(*flip)
+	}
+	// The first status is published too, so that new live clients can
+	// start from it.
+	if flip != nil || !h.hub.seeded() {
+		h.decorate(td)
+		if b, err := json.Marshal(td); err == nil {
+			h.hub.publish(b, started)
 		}
 	}
 }
 
-// internalError responds with a 500 code and the given message.
-func internalError(w http.ResponseWriter, format string, v ...interface{}) {
-	error := fmt.Sprintf(format, v...)
-	log.Print(error)
-	http.Error(w, error, http.StatusInternalServerError)
+// alerts fetches the provider's alerts for a request, within the fetch
+// timeout. It fails with errFeedMuted, without fetching, if the feed is
+// muted.
+func (h *handler) alerts(r *http.Request) ([]*Alert, error) {
+	if h.feedMuted.Load() {
+		return nil, errFeedMuted
+	}
+	ctx := r.Context()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	return h.provider.Alerts(ctx)
 }
 
-// logged logs the HTTP request, respecting the X-Forwarded-For header to support
-// running behind a proxy.
-func logged(hf http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		remote := strings.Join(r.Header["X-Forwarded-For"], " ")
-		if remote == "" {
-			remote = r.RemoteAddr
+// outranks reports whether status a should represent the road over b. It
+// follows severity, except that Unknown ranks just above Open: a road with a
+// segment nobody has reported on can't be called open, but a segment known
+// to be affected matters more.
+func outranks(a, b Status) bool {
+	rank := func(s Status) int {
+		if s == StatusUnknown {
+			return 2*int(StatusOpen) + 1
 		}
-		log.Printf("%s %s %s %s", remote, r.Method, r.URL, r.UserAgent())
-		hf(w, r)
+		return 2 * int(s)
+	}
+	return rank(a) > rank(b)
+}
+
+// evaluate computes the status of each segment from the provider's alerts.
+func (h *handler) evaluate(alerts []*Alert) *templateData {
+	td := &templateData{Road: h.road}
+	var worst *segmentData
+	for _, seg := range h.segments {
+		sd := h.segmentStatus(seg, alerts)
+		if worst == nil || outranks(sd.Status, worst.Status) {
+			worst = sd
+		}
+		td.Segments = append(td.Segments, sd)
+	}
+	td.Status = worst.Status
+	td.Reason = worst.Reason
+	td.Detail = worst.Detail
+	td.Description = worst.Description
+	td.Link = worst.Link
+	td.Published = worst.Published
+	td.since = worst.published
+	if td.Status == StatusUnknown {
+		td.Unknown = fmt.Sprintf("No current alert mentions %s.", worst.Name)
+	}
+	if len(td.Segments) == 1 {
+		td.Segments = nil
+	}
+	return td
+}
+
+// segmentStatus computes the status of a single segment. A segment is
+// assumed to be open (or unknown, if seg.DefaultUnknown) unless an alert
+// mentions it, in which case the latest such alert determines its status.
+func (h *handler) segmentStatus(seg Segment, alerts []*Alert) *segmentData {
+	sd := &segmentData{Name: seg.Name, Status: StatusOpen}
+	if seg.DefaultUnknown {
+		sd.Status = StatusUnknown
+	}
+	if latest := deciding(seg, alerts); latest != nil {
+		sd.Status = latest.Status
+		sd.Reason = latest.Reason
+		sd.Detail = latest.Title
+		sd.Description = latest.Description
+		sd.Link = latest.Link
+		if latest.Published != nil {
+			sd.Published = latest.Published.In(h.loc).Format(time.RFC1123)
+			sd.published = *latest.Published
+		}
+	}
+	return sd
+}
+
+// deciding returns the latest alert that mentions seg, if any.
+func deciding(seg Segment, alerts []*Alert) *Alert {
+	var latest *Alert
+	for _, a := range alerts {
+		if seg.matches(a) && newer(a, latest) {
+			latest = a
+		}
+	}
+	return latest
+}
+
+// matches reports whether an alert applies to the segment.
+func (seg Segment) matches(a *Alert) bool {
+	return strings.Contains(a.scope(), seg.Match)
+}
+
+// newer reports whether a was published after b. Dated alerts are newer than
+// undated ones, and ties go to b so that, when iterating, the earliest
+// alert in feed order wins. This keeps the selection deterministic without
+// relying on the feed being sorted.
+func newer(a, b *Alert) bool {
+	switch {
+	case b == nil:
+		return true
+	case a.Published == nil:
+		return false
+	case b.Published == nil:
+		return true
+	default:
+		return a.Published.After(*b.Published)
 	}
 }
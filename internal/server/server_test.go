@@ -3,10 +3,14 @@ package server
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -53,6 +57,9 @@ func startTestServer(t *testing.T, h http.Handler) string {
 		s.Serve(l)
 	}()
 	t.Cleanup(func() {
+		// Concurrent requests can leave spare connections that were dialed
+		// but never used, which Shutdown would wait five seconds for.
+		http.DefaultClient.CloseIdleConnections()
 		s.Shutdown(context.Background())
 		wg.Wait()
 	})
@@ -116,6 +123,27 @@ func TestOpen(t *testing.T) {
 			Link:  link,
 		}},
 		open: false,
+	}, {
+		desc: "latest by publish time",
+		items: []*feeds.Item{{
+			Title:   "Open - 124th",
+			Link:    link,
+			Created: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, {
+			Title:   "Closed - 124th",
+			Link:    link,
+			Created: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		open: false,
+	}, {
+		desc: "description",
+		items: []*feeds.Item{{
+			Title:       "Closed - 124th",
+			Link:        link,
+			Description: "<p>Detour via <b>SR 203</b><script>x</script></p>",
+		}},
+		open:   false,
+		detail: "Detour via SR 203",
 	}, {
 		desc: "updated at",
 		items: []*feeds.Item{{
@@ -181,3 +209,462 @@ func TestFavicon(t *testing.T) {
 		t.Fatalf("Expected OK, got %d", sc)
 	}
 }
+
+func TestGCPLog(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Override:   Open,
+		Road:       "124th",
+		LogFormat:  GCPLog,
+		GCPProject: "proj",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	out := &bytes.Buffer{}
+	h.(*handler).logOut = out
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Cloud-Trace-Context", "abc123/456;o=1")
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	var e gcpEntry
+	if err := json.Unmarshal(out.Bytes(), &e); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", out, err)
+	}
+	if e.Severity != "INFO" {
+		t.Errorf("Expected INFO severity, got %q", e.Severity)
+	}
+	if e.HTTPRequest == nil || e.HTTPRequest.Status != http.StatusOK {
+		t.Errorf("Expected httpRequest with status 200, got %+v", e.HTTPRequest)
+	}
+	if want := "projects/proj/traces/abc123"; e.Trace != want {
+		t.Errorf("Expected trace %q, got %q", want, e.Trace)
+	}
+	if want := "00000000000001c8"; e.SpanID != want {
+		t.Errorf("Expected span %q, got %q", want, e.SpanID)
+	}
+	// X-Forwarded-For is only believed from trusted proxies.
+	if e.HTTPRequest != nil && e.HTTPRequest.RemoteIP != "192.0.2.1" {
+		t.Errorf("Expected remoteIp 192.0.2.1, got %q", e.HTTPRequest.RemoteIP)
+	}
+}
+
+type fakeProvider struct {
+	alerts []*Alert
+	mu     sync.Mutex
+	err    error
+	calls  int
+}
+
+func (f *fakeProvider) Alerts(ctx context.Context) ([]*Alert, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.alerts, f.err
+}
+
+// fetches returns how many times Alerts has been called.
+func (f *fakeProvider) fetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *fakeProvider) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeProvider) set(alerts ...*Alert) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.alerts = alerts
+}
+
+func (f *fakeProvider) Source() (string, string) {
+	return "Fake County", "http://fake.example"
+}
+
+func TestProvider(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Flooded - 124th"}}},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"124th is Closed", "Flooded - 124th", "Fake County"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body missing %q: %s", want, body)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{
+			{Status: StatusAdvisory, Title: "Water over roadway - 124th at the bridge"},
+			{Status: StatusClosed, Title: "Closed - 124th west of SR203"},
+		}},
+		Road: "124th",
+		Segments: []Segment{
+			{Name: "West of SR203", Match: "west of SR203"},
+			{Name: "Bridge", Match: "at the bridge"},
+			{Name: "East", Match: "east of the river"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	td := h.(*handler).evaluate(h.(*handler).provider.(*fakeProvider).alerts)
+	if td.Status != StatusClosed {
+		t.Errorf("Expected overall status closed, got %s", td.Status)
+	}
+	want := []Status{StatusClosed, StatusAdvisory, StatusOpen}
+	if len(td.Segments) != len(want) {
+		t.Fatalf("Expected %d segments, got %d", len(want), len(td.Segments))
+	}
+	for i, w := range want {
+		if got := td.Segments[i].Status; got != w {
+			t.Errorf("Segment %q: expected %s, got %s", td.Segments[i].Name, w, got)
+		}
+	}
+}
+
+func TestBundle(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{
+		{Status: StatusClosed, Title: "Closed - Tolt Hill Rd"},
+	}}
+	h, err := NewHandler(&Options{
+		Provider: fp,
+		Road:     "124th",
+		Bundle: &Bundle{
+			Name: "Snoqualmie Valley",
+			Path: "/valley",
+			Roads: []Segment{
+				{Name: "124th", Match: "124th"},
+				{Name: "Tolt Hill Rd", Match: "Tolt Hill"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/valley", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"Snoqualmie Valley", `status-open">open`, `status-closed">closed`, "Closed - Tolt Hill Rd"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body missing %q: %s", want, body)
+		}
+	}
+
+	fp.fail(errors.New("connection refused"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/valley", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when the feed is down, got %d", rec.Code)
+	}
+	body = rec.Body.String()
+	if strings.Contains(body, "connection refused") || strings.Count(body, `status-unknown">unknown`) != 2 {
+		t.Errorf("Expected every road unknown without the raw error: %s", body)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	h, err := NewHandler(&Options{})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/schema", nil))
+	var s struct {
+		Version    int `json:"version"`
+		Properties map[string]struct {
+			Type string   `json:"type"`
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", rec.Body, err)
+	}
+	if s.Version != SchemaVersion {
+		t.Errorf("Expected version %d, got %d", SchemaVersion, s.Version)
+	}
+	if st := s.Properties["status"]; st.Type != "string" || len(st.Enum) != 5 {
+		t.Errorf("Expected status to be a string enum, got %+v", st)
+	}
+	if seg := s.Properties["segments"]; seg.Type != "array" {
+		t.Errorf("Expected segments to be an array, got %+v", seg)
+	}
+	if want := []string{"road", "status"}; fmt.Sprint(s.Required) != fmt.Sprint(want) {
+		t.Errorf("Expected required %v, got %v", want, s.Required)
+	}
+}
+
+func TestStatusHeader(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	for _, path := range []string{"/", "/api/v1/status"} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if got := rec.Header().Get("X-Road-Status"); got != "closed" {
+				t.Errorf("%s %s: expected X-Road-Status closed, got %q", method, path, got)
+			}
+			if method == http.MethodHead && rec.Body.Len() != 0 {
+				t.Errorf("%s %s: expected empty body, got %s", method, path, rec.Body)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	var td templateData
+	if err := json.Unmarshal(rec.Body.Bytes(), &td); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %v", rec.Body, err)
+	}
+	if td.Status != StatusClosed || td.Detail != "Closed - 124th" {
+		t.Errorf("Unexpected status: %+v", td)
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	h, err := NewHandler(&Options{Override: Open, Road: "124th", CanonicalHost: "124th.info"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	tests := []struct {
+		host     string
+		proto    string
+		code     int
+		location string
+	}{
+		{"124th.info", "", http.StatusOK, ""},
+		{"124TH.info", "", http.StatusOK, ""},
+		{"www.124th.info", "https", http.StatusMovedPermanently, "https://124th.info/valley?x=1"},
+		{"10.0.0.1:8080", "", http.StatusMovedPermanently, "http://124th.info/valley?x=1"},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/valley?x=1", nil)
+		r.Host = tc.host
+		if tc.proto != "" {
+			r.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.host, tc.code, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tc.location {
+			t.Errorf("%s: expected location %q, got %q", tc.host, tc.location, got)
+		}
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status.txt", nil))
+	if got, want := rec.Body.String(), "CLOSED\nClosed - 124th\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	fp.set()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status.txt", nil))
+	if got, want := rec.Body.String(), "OPEN\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th", WarmUp: true})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected /readyz to become ready, got %d", rec.Code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.(*handler).tracker.mu.Lock()
+	defer h.(*handler).tracker.mu.Unlock()
+	if !h.(*handler).tracker.observed {
+		t.Error("Expected the startup poll to seed the tracker")
+	}
+}
+
+func TestUnknown(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{err: errors.New("connection refused")},
+		Road:     "124th",
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Road-Status"); got != "unknown" {
+		t.Errorf("Expected X-Road-Status unknown, got %q", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"124th status is Unknown", "The Fake County road alert feed"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Body missing %q: %s", want, body)
+		}
+	}
+	if strings.Contains(body, "connection refused") {
+		t.Errorf("Body leaks the provider error: %s", body)
+	}
+}
+
+// TestUnknownAPI checks that the API endpoints answer a feed failure the
+// way / does: the Unknown status, with 503.
+func TestUnknownAPI(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider: &fakeProvider{err: errors.New("connection refused")},
+		Road:     "124th",
+		ShareKey: []byte("secret"),
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	want := serve(http.MethodGet, "/").Body.String()
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/api/v1/status", want},
+		{http.MethodGet, "/api/v1/feed", want},
+		{http.MethodPost, "/share", want},
+		{http.MethodGet, "/api/v1/device", `{"s":0,"a":-1}` + "\n"},
+		{http.MethodGet, "/status.txt", "UNKNOWN\n"},
+	} {
+		rec := serve(req.method, req.path)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503, got %d", req.path, rec.Code)
+		}
+		if got := rec.Header().Get("X-Road-Status"); got != "unknown" {
+			t.Errorf("%s: expected X-Road-Status unknown, got %q", req.path, got)
+		}
+		if got := rec.Body.String(); got != req.body {
+			t.Errorf("%s: expected %s, got %s", req.path, req.body, got)
+		}
+	}
+}
+
+// hangingProvider doesn't answer until the request is abandoned.
+type hangingProvider struct{}
+
+func (hangingProvider) Alerts(ctx context.Context) ([]*Alert, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (hangingProvider) Source() (string, string) {
+	return "Slow County", ""
+}
+
+func TestFetchTimeout(t *testing.T) {
+	h, err := NewHandler(&Options{
+		Provider:     hangingProvider{},
+		Road:         "124th",
+		FetchTimeout: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected an Unknown status after the timeout, got %d", rec.Code)
+	}
+}
+
+func TestDefaultUnknown(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - Tolt Hill Rd"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", DefaultUnknown: true})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+	rec := get()
+	if got := rec.Header().Get("X-Road-Status"); got != "unknown" {
+		t.Errorf("Expected unknown while no alert mentions the road, got %q", got)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 since the feed is fine, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "No current alert mentions 124th.") {
+		t.Errorf("Expected the reason on the page, got: %s", body)
+	}
+	fp.set(&Alert{Status: StatusOpen, Title: "Reopened - 124th"})
+	if got := get().Header().Get("X-Road-Status"); got != "open" {
+		t.Errorf("Expected open once an alert says so, got %q", got)
+	}
+}
+
+func TestUnknownSegmentRank(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{
+		Provider: fp,
+		Road:     "Valley",
+		Segments: []Segment{
+			{Name: "124th", Match: "124th"},
+			{Name: "Tolt Hill Rd", Match: "Tolt Hill", DefaultUnknown: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	hd := h.(*handler)
+
+	// Nobody has reported on Tolt Hill, so the valley can't be called open.
+	td := hd.evaluate(nil)
+	if td.Status != StatusUnknown || td.Unknown != "No current alert mentions Tolt Hill Rd." {
+		t.Errorf("Expected unknown naming the segment, got %s %q", td.Status, td.Unknown)
+	}
+	// But a known closure matters more.
+	td = hd.evaluate([]*Alert{{Status: StatusClosed, Title: "Closed - 124th"}})
+	if td.Status != StatusClosed {
+		t.Errorf("Expected closed to outrank unknown, got %s", td.Status)
+	}
+	td = hd.evaluate([]*Alert{{Status: StatusOpen, Title: "Open - Tolt Hill"}})
+	if td.Status != StatusOpen {
+		t.Errorf("Expected open once every segment is known, got %s", td.Status)
+	}
+}
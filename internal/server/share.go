@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// snapshot is a frozen status, encoded into a share URL. Share URLs are
+// self-contained and signed, so they need no storage and can't be altered.
+type snapshot struct {
+	Data *templateData `json:"d"`
+	At   time.Time     `json:"t"`
+}
+
+// encodeSnapshot returns the signed ID for a snapshot: the base64url JSON
+// payload and its HMAC-SHA256, separated by a dot.
+func encodeSnapshot(key []byte, s *snapshot) (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + sign(key, payload), nil
+}
+
+// decodeSnapshot verifies and decodes a snapshot ID.
+func decodeSnapshot(key []byte, id string) (*snapshot, error) {
+	payload, sig, ok := strings.Cut(id, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(sign(key, payload))) {
+		return nil, errors.New("invalid snapshot signature")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	s := &snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Data == nil {
+		return nil, errors.New("empty snapshot")
+	}
+	return s, nil
+}
+
+func sign(key []byte, payload string) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// freeze clears the parts of td that only make sense on the live page: the
+// share button and the crowd report form and tallies.
+func freeze(td *templateData) {
+	td.Shareable = false
+	td.CrowdReports = false
+	td.Reports = nil
+}
+
+// share freezes the current status and redirects to its snapshot URL.
+func (h *handler) share(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	td, err := h.current(r)
+	if err != nil {
+		h.unavailableJSON(w, r, err)
+		return
+	}
+	freeze(td)
+	id, err := encodeSnapshot(h.shareKey, &snapshot{Data: td, At: time.Now()})
+	if err != nil {
+		h.internalError(w, r, "internal error: %v", err)
+		return
+	}
+	http.Redirect(w, r, "/s/"+id, http.StatusSeeOther)
+}
+
+// snapshotPage renders a shared snapshot.
+func (h *handler) snapshotPage(w http.ResponseWriter, r *http.Request) {
+	s, err := decodeSnapshot(h.shareKey, strings.TrimPrefix(r.URL.Path, "/s/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	td := s.Data
+	td.Snapshot = s.At.In(h.loc).Format("3:04 PM on Mon, Jan 2, 2006")
+	// Snapshots never change, so they can be cached indefinitely.
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.templ.Execute(w, td); err != nil {
+		h.internalError(w, r, "internal error: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShare(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", ShareKey: []byte("secret"), CrowdReports: true})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/share", nil))
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected 303, got %d", rec.Code)
+	}
+	loc := rec.Header().Get("Location")
+
+	// The snapshot shows the status at share time even after it changes.
+	fp.set(&Alert{Status: StatusOpen, Title: "Reopened - 124th"})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, loc, nil))
+	body := rec.Body.String()
+	for _, want := range []string{"124th is Closed", "Snapshot of the status"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Snapshot missing %q: %s", want, body)
+		}
+	}
+	for _, live := range []string{`action="/report"`, `action="/share"`} {
+		if strings.Contains(body, live) {
+			t.Errorf("Snapshot shouldn't have the live form %s", live)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected an HTML Content-Type, got %q", ct)
+	}
+
+	// Tampered snapshots are rejected.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, loc+"x", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a tampered snapshot, got %d", rec.Code)
+	}
+}
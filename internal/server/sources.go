@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+// errFeedMuted is returned instead of alerts while the feed is muted.
+var errFeedMuted = errors.New("the road alert feed is muted")
+
+// mutedUnknown explains the Unknown status while the feed is muted.
+const mutedUnknown = "The road alert feed has been paused by the site's operator."
+
+// source is a status source that the admin API can mute at runtime.
+type source struct {
+	name  string
+	muted *atomic.Bool
+}
+
+// sources lists the sources that can be muted, in display order. Crowd
+// reports are only listed if they're enabled.
+func (h *handler) sources() []source {
+	srcs := []source{{"feed", &h.feedMuted}}
+	if h.reports != nil {
+		srcs = append(srcs, source{"reports", &h.reportsMuted})
+	}
+	return srcs
+}
+
+// setSources mutes or unmutes sources from form values named after them,
+// e.g. feed=off or reports=on, then writes each source's state. Sources not
+// named are left alone, so a GET just shows the state.
+//
+// A muted feed isn't consulted: the road is Unknown until it's unmuted or
+// an override is set. Muted crowd reports are hidden and not accepted.
+func (h *handler) setSources(w http.ResponseWriter, r *http.Request) {
+	srcs := h.sources()
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Validate everything before changing anything.
+		mute := make(map[string]bool)
+		for _, s := range srcs {
+			switch v := r.PostForm.Get(s.name); v {
+			case "":
+			case "on":
+				mute[s.name] = false
+			case "off":
+				mute[s.name] = true
+			default:
+				http.Error(w, fmt.Sprintf("%s must be on or off, not %q", s.name, v), http.StatusBadRequest)
+				return
+			}
+		}
+		for k := range r.PostForm {
+			if _, ok := mute[k]; !ok && r.PostForm.Get(k) != "" {
+				http.Error(w, fmt.Sprintf("unknown source %q", k), http.StatusBadRequest)
+				return
+			}
+		}
+		for _, s := range srcs {
+			if m, ok := mute[s.name]; ok && s.muted.Swap(m) != m {
+				log.Printf("Source %s turned %s by %s", s.name, onOff(!m), remoteAddr(r))
+			}
+		}
+	}
+	for _, s := range srcs {
+		fmt.Fprintln(w, s.name, onOff(!s.muted.Load()))
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
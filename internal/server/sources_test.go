@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSources(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", AdminToken: "s3cret", CrowdReports: true})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	set := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/admin/sources", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := set(url.Values{"feed": {"sideways"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad value, got %d", rec.Code)
+	}
+	if rec := set(url.Values{"gauge": {"off"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown source, got %d", rec.Code)
+	}
+
+	rec := set(url.Values{"feed": {"off"}, "reports": {"off"}})
+	if got := rec.Body.String(); got != "feed off\nreports off\n" {
+		t.Errorf("Expected both sources off, got %q", got)
+	}
+	if got := get("/status.txt").Header().Get("X-Road-Status"); got != "unknown" {
+		t.Errorf("Expected unknown with the feed muted, got %s", got)
+	}
+	if body := get("/").Body.String(); strings.Contains(body, `action="/report"`) {
+		t.Error("Expected the report form to be hidden while reports are muted")
+	}
+	r := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader("status=open"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a report while muted, got %d", rec.Code)
+	}
+
+	set(url.Values{"feed": {"on"}})
+	if got := get("/status.txt").Header().Get("X-Road-Status"); got != "closed" {
+		t.Errorf("Expected the feed's status once unmuted, got %s", got)
+	}
+	if cs := h.(*handler).tracker.changesSince(time.Time{}); len(cs) != 0 {
+		t.Errorf("Expected muting not to be recorded as a change, got %+v", cs)
+	}
+}
+
+func TestMutedFeedIsNotFetched(t *testing.T) {
+	fp := &fakeProvider{alerts: []*Alert{{Status: StatusClosed, Title: "Closed - 124th"}}}
+	h, err := NewHandler(&Options{
+		Provider:   fp,
+		Road:       "124th",
+		AdminToken: "s3cret",
+		Bundle:     &Bundle{Name: "Valley", Path: "/valley", Roads: []Segment{{Name: "124th", Match: "124th"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	h.(*handler).feedMuted.Store(true)
+	paths := []string{"/", "/api/v1/status", "/api/v1/device", "/api/v1/feed", "/status.txt", "/valley"}
+	for _, path := range paths {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code >= 500 && rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected success or 503 while muted, got %d", path, rec.Code)
+		}
+	}
+	if n := fp.fetches(); n != 0 {
+		t.Errorf("Expected no fetches while muted, got %d", n)
+	}
+}
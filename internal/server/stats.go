@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// closureStats are derived closure metrics over the period this server has
+// been observing the road. A closure is any time the road is StatusClosed.
+type closureStats struct {
+	Status        Status    `json:"status"`
+	ObservedSince time.Time `json:"observedSince"`
+	// CurrentClosureSeconds is how long the current closure has lasted,
+	// counted from when it was first observed.
+	CurrentClosureSeconds int64 `json:"currentClosureSeconds,omitempty"`
+	// SinceLastClosureSeconds is how long ago the last closure ended.
+	SinceLastClosureSeconds int64 `json:"sinceLastClosureSeconds,omitempty"`
+	// Closures counts closures that began while observing.
+	Closures      int     `json:"closures"`
+	PercentClosed float64 `json:"percentClosed"`
+}
+
+// stats computes closure metrics from the tracker's history.
+func (t *tracker) stats() *closureStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	cs := &closureStats{Status: t.status, ObservedSince: t.first}
+	if !t.observed {
+		return cs
+	}
+
+	// Walk the intervals between changes, starting from the status first
+	// observed.
+	status := t.status
+	if len(t.history) > 0 {
+		status = t.history[0].From
+	}
+	start := t.first
+	var closed time.Duration
+	var lastEnd time.Time
+	for _, c := range t.history {
+		if status == StatusClosed {
+			closed += c.At.Sub(start)
+			lastEnd = c.At
+		}
+		if c.To == StatusClosed {
+			cs.Closures++
+		}
+		status = c.To
+		start = c.At
+	}
+	if status == StatusClosed {
+		closed += now.Sub(start)
+		cs.CurrentClosureSeconds = int64(now.Sub(start) / time.Second)
+	} else if !lastEnd.IsZero() {
+		cs.SinceLastClosureSeconds = int64(now.Sub(lastEnd) / time.Second)
+	}
+	if total := now.Sub(t.first); total > 0 {
+		cs.PercentClosed = 100 * float64(closed) / float64(total)
+	}
+	return cs
+}
+
+// stats serves closure metrics as JSON.
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.tracker.stats())
+}
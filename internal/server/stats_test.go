@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	now := time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)
+	tr := newTracker()
+	tr.now = func() time.Time { return now }
+
+	step := func(d time.Duration, s Status) {
+		now = now.Add(d)
+		tr.observe(Change{To: s}, now)
+	}
+	step(0, StatusOpen)
+	step(2*time.Hour, StatusClosed)
+	step(3*time.Hour, StatusOpen)
+	now = now.Add(5 * time.Hour)
+
+	cs := tr.stats()
+	if cs.Closures != 1 {
+		t.Errorf("Expected 1 closure, got %d", cs.Closures)
+	}
+	if cs.PercentClosed != 30 {
+		t.Errorf("Expected 30%% closed, got %v", cs.PercentClosed)
+	}
+	if want := int64(5 * 60 * 60); cs.SinceLastClosureSeconds != want {
+		t.Errorf("Expected %d seconds since last closure, got %d", want, cs.SinceLastClosureSeconds)
+	}
+
+	step(0, StatusClosed)
+	now = now.Add(time.Hour)
+	cs = tr.stats()
+	if want := int64(60 * 60); cs.CurrentClosureSeconds != want {
+		t.Errorf("Expected %d second closure, got %d", want, cs.CurrentClosureSeconds)
+	}
+	if cs.SinceLastClosureSeconds != 0 {
+		t.Errorf("Expected no time since last closure while closed, got %d", cs.SinceLastClosureSeconds)
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import "fmt"
+
+// Status is the graded condition of a road.
+type Status int
+
+const (
+	// StatusUnknown means no source produced usable data.
+	StatusUnknown Status = iota
+	// StatusOpen means the road is passable.
+	StatusOpen
+	// StatusAdvisory means the road is open but there is water on the
+	// roadway or a similar hazard.
+	StatusAdvisory
+	// StatusPartial means the road is restricted but not fully closed, e.g.
+	// one lane open with flaggers or local access only.
+	StatusPartial
+	// StatusClosed means the road is closed.
+	StatusClosed
+)
+
+// statuses lists every Status, from least to most severe.
+var statuses = []Status{StatusUnknown, StatusOpen, StatusAdvisory, StatusPartial, StatusClosed}
+
+// String returns the lowercase name of the status, e.g. "open".
+func (s Status) String() string {
+	switch s {
+	case StatusOpen:
+		return "open"
+	case StatusAdvisory:
+		return "advisory"
+	case StatusPartial:
+		return "partial"
+	case StatusClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseStatus parses the lowercase name of a status, as returned by String.
+func ParseStatus(s string) (Status, error) {
+	for _, st := range statuses {
+		if s == st.String() {
+			return st, nil
+		}
+	}
+	return StatusUnknown, fmt.Errorf("unknown status %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Status) UnmarshalText(b []byte) error {
+	st, err := ParseStatus(string(b))
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
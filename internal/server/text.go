@@ -0,0 +1,61 @@
+package server
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// plainText extracts the readable text from an HTML fragment, dropping
+// markup, scripts, and styles, and collapsing whitespace. Block-level
+// elements and <br> become line breaks. The result is plain text and must
+// still be escaped before being rendered.
+func plainText(fragment string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return ""
+			}
+			return collapse(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style":
+				skip++
+			case "br", "p", "div", "li", "tr":
+				b.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style":
+				if skip > 0 {
+					skip--
+				}
+			case "p", "div", "li", "tr":
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// collapse trims each line, collapses runs of spaces within it, and drops
+// empty lines.
+func collapse(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,21 @@
+package server
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Road closed", "Road closed"},
+		{"<p>Closed due to <b>flooding</b>.</p><p>Detour via SR 203.</p>", "Closed due to flooding.\nDetour via SR 203."},
+		{"Expected duration:<br/>  2   days", "Expected duration:\n2 days"},
+		{"<script>alert(1)</script>Safe<style>p{}</style>", "Safe"},
+		{"Fish &amp; chips &lt;b&gt;", "Fish & chips <b>"},
+	}
+	for _, tc := range tests {
+		if got := plainText(tc.in); got != tc.want {
+			t.Errorf("plainText(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
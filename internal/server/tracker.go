@@ -0,0 +1,90 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// maxHistory bounds the number of changes the tracker remembers.
+const maxHistory = 1000
+
+// tracker records when this server observes the road's status change,
+// independently of the feed's publish times, which often reflect unrelated
+// edits to an alert. History is kept in memory and starts over when the
+// server restarts.
+type tracker struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	observed bool
+	first    time.Time
+	status   Status
+	changed  time.Time
+	seen     time.Time
+	fetched  time.Time
+	history  []Change
+	// changes is closed and replaced whenever the status changes, to wake
+	// up long-polling clients.
+	changes chan struct{}
+}
+
+// newTracker returns a tracker that has not yet observed a status.
+func newTracker() *tracker {
+	return &tracker{now: time.Now, changes: make(chan struct{})}
+}
+
+// observe records the current status, c.To, and returns when it last
+// changed. The zero time is returned until a change has been observed, since
+// the first observation says nothing about when the status began. If this
+// observation is a change, it is returned with From, After, and At filled in.
+//
+// started is when the fetch that produced c began. Concurrent fetches can
+// finish out of order, so a result that started before the last one recorded
+// is stale: it's ignored, and ok is false.
+func (t *tracker) observe(c Change, started time.Time) (changed time.Time, flip *Change, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if started.Before(t.fetched) {
+		return t.changed, nil, false
+	}
+	t.fetched = started
+	now := t.now()
+	if !t.observed {
+		t.observed = true
+		t.first = now
+	} else if c.To != t.status {
+		t.changed = now
+		c.From = t.status
+		c.After = t.seen
+		c.At = now
+		flip = &c
+		t.history = append(t.history, c)
+		if len(t.history) > maxHistory {
+			t.history = t.history[len(t.history)-maxHistory:]
+		}
+		close(t.changes)
+		t.changes = make(chan struct{})
+	}
+	t.status = c.To
+	t.seen = now
+	return t.changed, flip, true
+}
+
+// next returns a channel that is closed the next time the status changes.
+func (t *tracker) next() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changes
+}
+
+// changesSince returns the recorded changes at or after since, oldest first.
+func (t *tracker) changesSince(since time.Time) []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var cs []Change
+	for _, c := range t.history {
+		if !c.At.Before(since) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
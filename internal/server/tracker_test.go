@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2021, 11, 15, 3, 41, 0, 0, time.UTC)
+	tr := newTracker()
+	tr.now = func() time.Time { return now }
+
+	if got, flip, _ := tr.observe(Change{To: StatusOpen}, now); !got.IsZero() || flip != nil {
+		t.Errorf("First observation: expected zero time, got %v %+v", got, flip)
+	}
+	got, flip, _ := tr.observe(Change{To: StatusClosed, Detail: "Closed - 124th"}, now)
+	if !got.Equal(now) || flip == nil || flip.From != StatusOpen || !flip.At.Equal(now) || flip.Detail != "Closed - 124th" {
+		t.Errorf("After change: expected %v from open, got %v %+v", now, got, flip)
+	}
+	changed := now
+	now = now.Add(time.Hour)
+	if got, flip, _ := tr.observe(Change{To: StatusClosed}, now); !got.Equal(changed) || flip != nil {
+		t.Errorf("Unchanged: expected %v, got %v %+v", changed, got, flip)
+	}
+	seen := now
+	now = now.Add(time.Hour)
+	if _, flip, _ := tr.observe(Change{To: StatusOpen}, now); flip == nil || !flip.After.Equal(seen) || !flip.At.Equal(now) {
+		t.Errorf("Reopened: expected change between %v and %v, got %+v", seen, now, flip)
+	}
+	if len(tr.history) != 2 {
+		t.Errorf("Expected two changes in history, got %d", len(tr.history))
+	}
+}
+
+func TestTrackerStale(t *testing.T) {
+	now := time.Date(2021, 11, 15, 3, 41, 0, 0, time.UTC)
+	tr := newTracker()
+	tr.now = func() time.Time { return now }
+
+	tr.observe(Change{To: StatusOpen}, now)
+	// A slow fetch that started first finishes after a newer one.
+	slow := now.Add(time.Second)
+	tr.observe(Change{To: StatusClosed}, now.Add(2*time.Second))
+	if _, flip, ok := tr.observe(Change{To: StatusOpen}, slow); ok || flip != nil {
+		t.Errorf("Stale result was recorded: %v %+v", ok, flip)
+	}
+	if tr.status != StatusClosed || len(tr.history) != 1 {
+		t.Errorf("Expected only the closure to be recorded, got %v %+v", tr.status, tr.history)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// watchdog notices when the upstream feed keeps answering but its content
+// stops changing while the road is affected, which suggests the county's
+// feed is stuck rather than that conditions are stable.
+type watchdog struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	threshold time.Duration
+	sum       [sha256.Size]byte
+	changed   time.Time
+	warned    bool
+}
+
+// newWatchdog returns a watchdog that fires after threshold of silence. A
+// zero threshold disables it.
+func newWatchdog(threshold time.Duration) *watchdog {
+	return &watchdog{now: time.Now, threshold: threshold}
+}
+
+// check records the latest alerts and returns how long the feed has been
+// silent if the silence should be reported. Each silent period is only
+// reported once; silence while the road is open is expected and ignored.
+func (w *watchdog) check(alerts []*Alert, status Status) (time.Duration, bool) {
+	if w.threshold == 0 {
+		return 0, false
+	}
+	h := sha256.New()
+	for _, a := range alerts {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%v\x00", a.Title, a.Scope, a.Description, a.Published)
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if sum != w.sum || w.changed.IsZero() {
+		w.sum = sum
+		w.changed = now
+		w.warned = false
+		return 0, false
+	}
+	silent := now.Sub(w.changed)
+	if status == StatusOpen || status == StatusUnknown || silent < w.threshold || w.warned {
+		return 0, false
+	}
+	w.warned = true
+	return silent, true
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	now := time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)
+	w := newWatchdog(6 * time.Hour)
+	w.now = func() time.Time { return now }
+	alerts := []*Alert{{Title: "Closed - 124th"}}
+
+	if _, warn := w.check(alerts, StatusClosed); warn {
+		t.Error("Expected no warning on first check")
+	}
+	now = now.Add(7 * time.Hour)
+	if _, warn := w.check(alerts, StatusOpen); warn {
+		t.Error("Expected no warning while open")
+	}
+	silent, warn := w.check(alerts, StatusClosed)
+	if !warn || silent != 7*time.Hour {
+		t.Errorf("Expected warning after 7h, got %v %t", silent, warn)
+	}
+	if _, warn := w.check(alerts, StatusClosed); warn {
+		t.Error("Expected a single warning per silent period")
+	}
+	now = now.Add(time.Hour)
+	if _, warn := w.check([]*Alert{{Title: "Reopened - 124th"}}, StatusOpen); warn {
+		t.Error("Expected no warning after the feed changed")
+	}
+}
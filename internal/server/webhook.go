@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds each webhook delivery.
+	webhookTimeout = 10 * time.Second
+	// maxDeliveries bounds the memory used by the delivery log.
+	maxDeliveries = 200
+)
+
+// delivery is a webhook delivery attempt, for debugging missed events.
+type delivery struct {
+	At     time.Time `json:"at"`
+	Target string    `json:"target"`
+	// PayloadSHA256 identifies the body sent, so a subscriber can match it
+	// against what they received.
+	PayloadSHA256 string `json:"payloadSHA256"`
+	// Status is the response's status code, or 0 if there was none.
+	Status    int    `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// deliveries is an in-memory log of the most recent webhook deliveries. It
+// doesn't survive a restart.
+type deliveries struct {
+	mu  sync.Mutex
+	log []delivery
+}
+
+func (d *deliveries) add(dl delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.log) == maxDeliveries {
+		d.log = append(d.log[:0], d.log[1:]...)
+	}
+	d.log = append(d.log, dl)
+}
+
+// recent returns the logged deliveries, newest first.
+func (d *deliveries) recent() []delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := make([]delivery, len(d.log))
+	for i, dl := range d.log {
+		r[len(r)-1-i] = dl
+	}
+	return r
+}
+
+// webhook returns an OnChange hook that POSTs each change to url as JSON.
+// Failures are logged, and every attempt is recorded in dl.
+func webhook(url string, dl *deliveries) func(Change) {
+	client := &http.Client{Timeout: webhookTimeout}
+	return func(c Change) {
+		b, err := json.Marshal(c)
+		if err != nil {
+			log.Printf("Failed to marshal change: %v", err)
+			return
+		}
+		sum := sha256.Sum256(b)
+		start := time.Now()
+		status, err := post(client, url, b)
+		d := delivery{
+			At:            start,
+			Target:        url,
+			PayloadSHA256: hex.EncodeToString(sum[:]),
+			Status:        status,
+			LatencyMs:     time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			d.Error = err.Error()
+			log.Printf("Webhook %s failed: %v", url, err)
+		}
+		dl.add(d)
+	}
+}
+
+// post sends a JSON body to url and checks for a 2xx response. It returns
+// the response's status code, if there was a response.
+func post(client *http.Client, url string, body []byte) (int, error) {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookLog serves the delivery log as JSON, newest first.
+func (h *handler) webhookLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.webhooks.recent())
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	got := make(chan Change, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Change
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("Failed to decode webhook body: %v", err)
+		}
+		got <- p
+	}))
+	defer hook.Close()
+
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th", Webhooks: []string{hook.URL, hook.URL}})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	get := func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	get()
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	get()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case p := <-got:
+			if p.To != StatusClosed || p.Source != "Fake County" || p.At.IsZero() {
+				t.Errorf("Unexpected payload: %+v", p)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a webhook delivery")
+		}
+	}
+}
+
+func TestWebhookLog(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusGone)
+	}))
+	defer broken.Close()
+
+	var dl deliveries
+	c := Change{Road: "124th", From: StatusOpen, To: StatusClosed}
+	webhook(ok.URL, &dl)(c)
+	webhook(broken.URL, &dl)(c)
+
+	got := dl.recent()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %+v", got)
+	}
+	if d := got[0]; d.Target != broken.URL || d.Status != http.StatusGone || d.Error == "" {
+		t.Errorf("Expected the failed delivery first, got %+v", d)
+	}
+	if d := got[1]; d.Target != ok.URL || d.Status != http.StatusOK || d.Error != "" {
+		t.Errorf("Expected a successful delivery, got %+v", d)
+	}
+	if got[0].PayloadSHA256 != got[1].PayloadSHA256 || len(got[0].PayloadSHA256) != 64 {
+		t.Errorf("Expected matching payload hashes, got %q and %q", got[0].PayloadSHA256, got[1].PayloadSHA256)
+	}
+
+	for i := 0; i < maxDeliveries; i++ {
+		dl.add(delivery{Target: "x"})
+	}
+	if got := dl.recent(); len(got) != maxDeliveries || got[len(got)-1].Target != "x" {
+		t.Errorf("Expected the log to keep only the latest %d deliveries", maxDeliveries)
+	}
+}
+
+func TestWebhookLogAdmin(t *testing.T) {
+	h, err := NewHandler(&Options{Provider: &fakeProvider{}, Road: "124th", AdminToken: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	h.(*handler).webhooks.add(delivery{Target: "https://hooks.example", Status: http.StatusOK})
+	get := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	if rec := get("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with the wrong token, got %d", rec.Code)
+	}
+	var got []delivery
+	if err := json.Unmarshal(get("s3cret").Body.Bytes(), &got); err != nil || len(got) != 1 || got[0].Target != "https://hooks.example" {
+		t.Errorf("Expected the logged delivery, got %+v (%v)", got, err)
+	}
+}
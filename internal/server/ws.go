@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// ws serves a WebSocket that sends the status, as the same JSON as
+// /api/v1/status, on connect and again whenever it changes. Like /events, it
+// starts from the last status published. Messages from clients are ignored.
+func (h *handler) ws(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.hub.subscribe()
+	if !ok {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "too many live connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.hub.unsubscribe(sub)
+
+	websocket.Server{
+		// The status is public, so any origin (or none, for kiosks and
+		// scripts) may connect.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			closed := make(chan struct{})
+			go func() {
+				io.Copy(io.Discard, conn)
+				close(closed)
+			}()
+			send := func(data []byte) bool {
+				conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				return websocket.Message.Send(conn, string(data)) == nil
+			}
+
+			var last []byte
+			if !h.hub.seeded() {
+				// As for /events, computing the status publishes it
+				// to this client too, unless the feed is muted.
+				td, err := h.current(r)
+				if err != nil {
+					h.warnf(r, "failed to fetch road alerts for /ws: %v", err)
+				} else if !h.hub.seeded() {
+					if last, err = json.Marshal(td); err != nil || !send(last) {
+						return
+					}
+				}
+			}
+			for {
+				select {
+				case data, ok := <-sub.updates:
+					if !ok {
+						h.warnf(r, "evicting /ws client that stopped reading")
+						return
+					}
+					if bytes.Equal(data, last) {
+						continue
+					}
+					last = data
+					if !send(data) {
+						return
+					}
+				case <-closed:
+					return
+				}
+			}
+		},
+	}.ServeHTTP(w, r)
+}
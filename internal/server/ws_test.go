@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	fp := &fakeProvider{}
+	h, err := NewHandler(&Options{Provider: fp, Road: "124th"})
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	server := startTestServer(t, h)
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server, "http")+"/ws", "", server)
+	if err != nil {
+		t.Fatalf("websocket.Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	var td templateData
+	if err := websocket.JSON.Receive(conn, &td); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if td.Status != StatusOpen {
+		t.Errorf("Expected open on connect, got %s", td.Status)
+	}
+
+	// A page view seeing the change pushes it to this client.
+	fp.set(&Alert{Status: StatusClosed, Title: "Closed - 124th"})
+	r, err := http.Get(server + "/")
+	if err != nil {
+		t.Fatalf("http.Get(/) failed: %v", err)
+	}
+	r.Body.Close()
+	if err := websocket.JSON.Receive(conn, &td); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if td.Status != StatusClosed {
+		t.Errorf("Expected closed after the change, got %s", td.Status)
+	}
+}
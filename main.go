@@ -1,22 +1,206 @@
 // flood is a little server that displays the latest information about
 // 124th during flood season. Updates are read from the KC road alert
-// RSS feed.
+// RSS feed by default; other regions can point it at their own feed with
+// --provider=rss.
 package main
 
 import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"jdtw.dev/flood/internal/server"
 )
 
 func main() {
 	var port = flag.Int("port", 8080, "Port to listen on")
+	var debugAddr = flag.String("debug-addr", "", "If set, serve net/http/pprof on this loopback address, e.g. localhost:6060")
+	var logFormat = flag.String("log-format", "text", "Log format: text or gcp")
+	var providerName = flag.String("provider", "kingcounty", "Road alert provider: kingcounty or rss")
+	var demo = flag.Bool("demo", false, "Cycle through scripted states instead of reading a real feed")
+	var demoInterval = flag.Duration("demo-interval", 30*time.Second, "How long each scripted state lasts in --demo mode")
+	var road = flag.String("road", "124th", "Road to report on, as it appears in alert titles")
+	var silence = flag.Duration("silence-threshold", 6*time.Hour, "Warn if the feed is unchanged this long while the road is affected; 0 disables")
+	var outage = flag.Int("outage-threshold", 3, "Show the last known status with a banner after this many consecutive feed failures; 0 disables")
+	var pollInterval = flag.Duration("poll-interval", 5*time.Minute, "Poll the provider in the background this often, to push changes to /events; 0 disables")
+	var activePollInterval = flag.Duration("active-poll-interval", time.Minute, "Background poll interval while the road isn't open; 0 keeps --poll-interval")
+	var fetchTimeout = flag.Duration("fetch-timeout", 5*time.Second, "How long a request waits on the feed before showing the last known status; 0 waits indefinitely")
+	var maxAge = flag.Duration("max-age", 0, "Let browsers and CDNs cache the status page and API for this long; 0 disables caching headers")
+	var canonicalHost = flag.String("canonical-host", "", "If set, redirect requests for any other host to this one")
+	var crowdReports = flag.Bool("crowd-reports", false, "Let visitors report whether they just drove the road")
+	var coalesce = flag.Duration("coalesce", 0, "Merge status changes within this window into a single --on-change or --webhook notification")
+	var userAgent = flag.String("user-agent", server.DefaultUserAgent, "User-Agent for feed and camera requests; include a contact URL or email so the feed's operator can reach you")
+	var rateLimit = flag.Float64("rate-limit", 0, "Requests per second allowed per client on average; 0 disables rate limiting")
+	var rateBurst = flag.Int("rate-burst", 20, "Requests a client may make at once before --rate-limit applies")
+	var maxStreams = flag.Int("max-streams", server.DefaultMaxStreams, "Maximum concurrent /events and /ws connections")
+	var defaultUnknown = flag.Bool("default-unknown", false, "Report --road as unknown rather than open while no alert mentions it, if no --segment is given; segments take a ,unknown suffix instead")
+	var timezone = flag.String("timezone", "America/Los_Angeles", "Timezone in which to display times")
+	var feedURLs []string
+	flag.Func("feed", "Feed URL; may be repeated to merge mirrors (required for the rss provider; overrides the kingcounty default)", func(v string) error {
+		feedURLs = append(feedURLs, v)
+		return nil
+	})
+	var keywords []server.Keyword
+	flag.Func("keyword", "Title phrase and the status it indicates, as phrase=status (rss provider); may be repeated, first match wins", func(v string) error {
+		phrase, status, ok := strings.Cut(v, "=")
+		if !ok || phrase == "" {
+			return fmt.Errorf("keyword %q is not of the form phrase=status", v)
+		}
+		st, err := server.ParseStatus(status)
+		if err != nil {
+			return err
+		}
+		keywords = append(keywords, server.Keyword{Phrase: phrase, Status: st})
+		return nil
+	})
+	var hooks []func(server.Change)
+	flag.Func("on-change", "Command to run on each status change, with the change as JSON on stdin and FLOOD_* environment variables; may be repeated", func(v string) error {
+		args := strings.Fields(v)
+		if len(args) == 0 {
+			return fmt.Errorf("empty command")
+		}
+		hooks = append(hooks, server.ExecHook(args[0], args[1:]...))
+		return nil
+	})
+	var origins []string
+	flag.Func("allow-origin", "Origin whose scripts may read the API, e.g. https://example.com, or * for any; may be repeated", func(v string) error {
+		origins = append(origins, v)
+		return nil
+	})
+	var outbound server.OutboundPolicy
+	flag.Func("allow-host", "Host that feeds and cameras may be fetched from; may be repeated (default any)", func(v string) error {
+		outbound.Hosts = append(outbound.Hosts, v)
+		return nil
+	})
+	flag.BoolVar(&outbound.AllowPrivate, "allow-private", false, "Allow fetching feeds and cameras from loopback and private network addresses")
+	var proxies []netip.Prefix
+	flag.Func("trusted-proxy", "Network of a proxy, e.g. a load balancer, whose X-Forwarded-For identifies clients, as a CIDR; may be repeated", func(v string) error {
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return err
+		}
+		proxies = append(proxies, p)
+		return nil
+	})
+	var webhooks []string
+	flag.Func("webhook", "URL to POST each status change to as JSON; may be repeated", func(v string) error {
+		webhooks = append(webhooks, v)
+		return nil
+	})
+	var segments []server.Segment
+	flag.Func("segment", "Road segment as name=match, or name=match,unknown to report it as unknown rather than open while no alert mentions it; may be repeated", func(v string) error {
+		name, match, ok := strings.Cut(v, "=")
+		if !ok || name == "" || match == "" {
+			return fmt.Errorf("segment %q is not of the form name=match", v)
+		}
+		seg := server.Segment{Name: name, Match: match}
+		if m, ok := strings.CutSuffix(match, ",unknown"); ok && m != "" {
+			seg.Match, seg.DefaultUnknown = m, true
+		}
+		segments = append(segments, seg)
+		return nil
+	})
+	var valleyUnknown []string
+	flag.Func("valley-unknown", "Match of a /valley road, e.g. Tolt Hill, to report as unknown rather than open while no alert mentions it (kingcounty provider); may be repeated", func(v string) error {
+		valleyUnknown = append(valleyUnknown, v)
+		return nil
+	})
 	flag.Parse()
 
+	var provider server.Provider
+	var bundle *server.Bundle
+	var cameras []server.Camera
+	if *demo {
+		*providerName = "demo"
+	}
+	switch *providerName {
+	case "kingcounty":
+		kc := server.KingCounty()
+		if feedURLs != nil {
+			kc.URLs = feedURLs
+		}
+		kc.UserAgent = *userAgent
+		provider = kc
+		bundle = &server.Bundle{
+			Name: "Snoqualmie Valley",
+			Path: "/valley",
+			Roads: []server.Segment{
+				{Name: "NE 124th St", Match: "124th"},
+				{Name: "Tolt Hill Rd", Match: "Tolt Hill"},
+				{Name: "NE Carnation Farm Rd", Match: "Carnation Farm"},
+			},
+		}
+		for _, m := range valleyUnknown {
+			i := slices.IndexFunc(bundle.Roads, func(r server.Segment) bool { return r.Match == m })
+			if i < 0 {
+				log.Fatalf("--valley-unknown %q matches no /valley road", m)
+			}
+			bundle.Roads[i].DefaultUnknown = true
+		}
+		const kcCamera = "https://info.kingcounty.gov/transportation/kcdot/Roads/TrafficCameras/ImageHandler/Handler.ashx?id="
+		cameras = []server.Camera{
+			{Group: "124th Cameras", Name: "203 & 124th Roundabout", URL: kcCamera + "CarDuv_SR203_124.jpg"},
+			{Group: "124th Cameras", Name: "West Snoqualmie & 124th", URL: kcCamera + "WSnoNE_124.jpg"},
+			{Group: "Woodinville Duvall Cameras", Name: "West Snoqualmie and Woodinville Duvall, SW corner", URL: kcCamera + "WSno_WoodDuv_swc.jpg"},
+			{Group: "Woodinville Duvall Cameras", Name: "West Snoqualmie and Woodinville Duvall, NE corner", URL: kcCamera + "Wsno_WoodDuv_nec.jpg"},
+		}
+	case "rss":
+		if feedURLs == nil {
+			log.Fatal("--feed is required for the rss provider")
+		}
+		if keywords == nil {
+			keywords = server.DefaultKeywords
+		}
+		rss := server.NewFeedProvider(keywords, feedURLs...)
+		rss.UserAgent = *userAgent
+		provider = rss
+	case "demo":
+		if *demoInterval <= 0 {
+			log.Fatal("--demo-interval must be positive")
+		}
+		provider = server.Demo(*road, *demoInterval)
+	default:
+		log.Fatalf("Unknown provider %q", *providerName)
+	}
+
+	if valleyUnknown != nil && bundle == nil {
+		log.Fatal("--valley-unknown requires the kingcounty provider")
+	}
+
+	if fp, ok := provider.(*server.FeedProvider); ok {
+		for _, u := range fp.URLs {
+			pu, err := url.Parse(u)
+			if err != nil {
+				log.Fatalf("Invalid feed URL: %v", err)
+			}
+			if err := outbound.Check(pu); err != nil {
+				log.Fatalf("Feed not allowed: %v", err)
+			}
+		}
+		fp.Client = outbound.Client(time.Minute)
+	}
+
+	var format server.LogFormat
+	switch *logFormat {
+	case "text":
+		format = server.TextLog
+	case "gcp":
+		format = server.GCPLog
+		log.SetFlags(0)
+		log.SetOutput(server.NewGCPWriter(os.Stderr))
+	default:
+		log.Fatalf("Unknown log format %q", *logFormat)
+	}
+
 	var override server.Override
 	switch os.Getenv("OVERRIDE") {
 	case "open":
@@ -25,15 +209,84 @@ func main() {
 		override = server.Closed
 	}
 
+	var hook func(server.Change)
+	if hooks != nil {
+		hook = func(c server.Change) {
+			for _, h := range hooks {
+				h(c)
+			}
+		}
+	}
+
 	handler, err := server.NewHandler(&server.Options{
-		Override: override,
-		FeedURL:  "https://gismaps.kingcounty.gov/roadalert/rss.aspx",
-		Road:     "124th",
-		Timezone: "America/Los_Angeles",
+		Override:       override,
+		Provider:       provider,
+		Road:           *road,
+		Segments:       segments,
+		DefaultUnknown: *defaultUnknown,
+		Bundle:         bundle,
+		Cameras:        cameras,
+		Timezone:       *timezone,
+
+		SilenceThreshold:   *silence,
+		OutageThreshold:    *outage,
+		FetchTimeout:       *fetchTimeout,
+		OnChange:           hook,
+		Webhooks:           webhooks,
+		CoalesceWindow:     *coalesce,
+		CanonicalHost:      *canonicalHost,
+		AllowedOrigins:     origins,
+		RateLimit:          *rateLimit,
+		RateBurst:          *rateBurst,
+		MaxStreams:         *maxStreams,
+		MaxAge:             *maxAge,
+		TrustedProxies:     proxies,
+		Outbound:           &outbound,
+		UserAgent:          *userAgent,
+		ShareKey:           []byte(os.Getenv("SHARE_KEY")),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		AdminKey:           []byte(os.Getenv("ADMIN_KEY")),
+		CrowdReports:       *crowdReports,
+		WarmUp:             true,
+		PollInterval:       *pollInterval,
+		ActivePollInterval: *activePollInterval,
+
+		LogFormat:  format,
+		GCPProject: os.Getenv("GOOGLE_CLOUD_PROJECT"),
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
+	if *debugAddr != "" {
+		if err := serveDebug(*debugAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
 	log.Printf("Listening on port %d", *port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), handler))
 }
+
+// serveDebug serves the profiling endpoints on addr in the background. addr
+// must be a loopback address so they're never exposed publicly.
+func serveDebug(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --debug-addr: %v", err)
+	}
+	if ip, err := netip.ParseAddr(host); host != "localhost" && (err != nil || !ip.IsLoopback()) {
+		return fmt.Errorf("--debug-addr %q is not a loopback address", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	log.Printf("Serving pprof on %s", ln.Addr())
+	go func() { log.Fatal(http.Serve(ln, mux)) }()
+	return nil
+}